@@ -2,12 +2,22 @@ package common
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 )
 
 type V2TokenResponse struct {
-	Token string `json:"token"`
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"` // OAuth2 compatible token services return the token in both fields
+}
+
+// BearerToken returns the token of the response, from whichever field the token service filled
+func (r *V2TokenResponse) BearerToken() string {
+	if r.Token != "" {
+		return r.Token
+	}
+	return r.AccessToken
 }
 
 func ValidateAuth(auth *authn.AuthConfig) error {
@@ -20,3 +30,42 @@ func ValidateAuth(auth *authn.AuthConfig) error {
 	}
 	return nil
 }
+
+// BearerChallenge is the token service of a registry as advertised by a WWW-Authenticate header
+// (e.g Bearer realm="https://harbor.example.com/service/token",service="harbor-registry",scope="repository:library/nginx:pull")
+type BearerChallenge struct {
+	Realm   string
+	Service string
+	Scope   string
+}
+
+// ParseBearerChallenge parses a WWW-Authenticate header, ok is false if it is not a bearer challenge with a realm
+func ParseBearerChallenge(header string) (challenge BearerChallenge, ok bool) {
+	scheme, params, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "bearer") {
+		return BearerChallenge{}, false
+	}
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if strings.HasPrefix(params, `"`) {
+			//quoted values may contain commas (e.g scopes of several repositories)
+			end := strings.Index(params[1:], `"`)
+			if end == -1 {
+				return BearerChallenge{}, false
+			}
+			value, params = params[1:end+1], params[end+2:]
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "realm":
+			challenge.Realm = value
+		case "service":
+			challenge.Service = value
+		case "scope":
+			challenge.Scope = value
+		}
+	}
+	return challenge, challenge.Realm != ""
+}
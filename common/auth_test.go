@@ -0,0 +1,31 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBearerChallenge(t *testing.T) {
+	challenge, ok := ParseBearerChallenge(`Bearer realm="https://harbor.example.com/service/token",service="harbor-registry",scope="repository:library/nginx:pull,push"`)
+	assert.True(t, ok)
+	assert.Equal(t, BearerChallenge{Realm: "https://harbor.example.com/service/token", Service: "harbor-registry", Scope: "repository:library/nginx:pull,push"}, challenge)
+
+	challenge, ok = ParseBearerChallenge(`bearer realm="https://quay.io/v2/auth", service=quay.io`)
+	assert.True(t, ok)
+	assert.Equal(t, BearerChallenge{Realm: "https://quay.io/v2/auth", Service: "quay.io"}, challenge)
+
+	_, ok = ParseBearerChallenge(`Basic realm="Harbor"`)
+	assert.False(t, ok)
+	_, ok = ParseBearerChallenge(`Bearer service="harbor-registry"`)
+	assert.False(t, ok)
+	_, ok = ParseBearerChallenge(`Bearer realm="https://unterminated`)
+	assert.False(t, ok)
+	_, ok = ParseBearerChallenge("")
+	assert.False(t, ok)
+}
+
+func TestV2TokenResponse(t *testing.T) {
+	assert.Equal(t, "a", (&V2TokenResponse{Token: "a", AccessToken: "b"}).BearerToken())
+	assert.Equal(t, "b", (&V2TokenResponse{AccessToken: "b"}).BearerToken())
+}
@@ -3,5 +3,7 @@ package common
 type CatalogOption struct {
 	IncludeLastModified bool
 	IsPublic            bool
-	Namespaces          string //scope for e.g cataloging just "armosec" images
+	Namespaces          string   //scope for e.g cataloging just "armosec" images
+	Projects            []string //scope for registries with projects (e.g harbor), project names or glob patterns (path.Match syntax)
+	IncludeProxyCache   bool     //include the proxy cache projects matched by Projects patterns, proxy cache projects named explicitly are always included
 }
@@ -0,0 +1,14 @@
+package common
+
+// DeleteOption controls tags and manifests deletion
+type DeleteOption struct {
+	DryRun bool // report what would be deleted without deleting it
+}
+
+// DeleteResult describes a deleted (or in dry-run, a deletable) tag or manifest
+type DeleteResult struct {
+	Repository string
+	Reference  string // the deleted tag or digest
+	Digest     string // the manifest digest the reference pointed to
+	DryRun     bool   // nothing was deleted
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// TagFilter selects the tags of a repository before any manifest is fetched, a nil filter keeps every tag
+type TagFilter struct {
+	Include                []string       // glob patterns (path.Match syntax), when set a tag must match one of them
+	Exclude                []string       // glob patterns of tags to skip
+	IncludeRegex           *regexp.Regexp // when set a tag must match it
+	ExcludeRegex           *regexp.Regexp // tags matching it are skipped
+	SemverOnly             bool           // keep only semantic version tags
+	ExcludeCosignArtifacts bool           // skip cosign signature, attestation and SBOM tags (sha256-<hex>.sig/.att/.sbom)
+}
+
+// Validate checks the syntax of the glob patterns
+func (f *TagFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tag pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the filter keeps tag
+func (f *TagFilter) Matches(tag string) bool {
+	if f == nil {
+		return true
+	}
+	if f.ExcludeCosignArtifacts && IsCosignTag(tag) {
+		return false
+	}
+	if len(f.Include) > 0 && !matchesAny(f.Include, tag) {
+		return false
+	}
+	if matchesAny(f.Exclude, tag) {
+		return false
+	}
+	if f.IncludeRegex != nil && !f.IncludeRegex.MatchString(tag) {
+		return false
+	}
+	if f.ExcludeRegex != nil && f.ExcludeRegex.MatchString(tag) {
+		return false
+	}
+	if f.SemverOnly {
+		if _, err := semver.NewVersion(tag); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the tags kept by the filter
+func (f *TagFilter) Apply(tags []string) []string {
+	if f == nil {
+		return tags
+	}
+	filtered := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if f.Matches(tag) {
+			filtered = append(filtered, tag)
+		}
+	}
+	return filtered
+}
+
+func matchesAny(patterns []string, tag string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, tag); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagFilter(t *testing.T) {
+	tags := []string{"latest", "v1.2.0", "1.3.0", "sha-abc", "pr-123", "sha256-abc.sig", "sha256-abc.att", "sha256-abc.sbom", "nightly"}
+
+	var noFilter *TagFilter
+	assert.Equal(t, tags, noFilter.Apply(tags))
+	assert.True(t, noFilter.Matches("anything"))
+	assert.Nil(t, noFilter.Validate())
+
+	filter := &TagFilter{ExcludeCosignArtifacts: true}
+	assert.Equal(t, []string{"latest", "v1.2.0", "1.3.0", "sha-abc", "pr-123", "nightly"}, filter.Apply(tags))
+
+	filter = &TagFilter{Exclude: []string{"sha-*", "pr-*"}, ExcludeCosignArtifacts: true}
+	assert.Equal(t, []string{"latest", "v1.2.0", "1.3.0", "nightly"}, filter.Apply(tags))
+
+	filter = &TagFilter{Include: []string{"v*", "latest"}}
+	assert.Equal(t, []string{"latest", "v1.2.0"}, filter.Apply(tags))
+
+	filter = &TagFilter{IncludeRegex: regexp.MustCompile(`^v?\d`), ExcludeRegex: regexp.MustCompile(`^v`)}
+	assert.Equal(t, []string{"1.3.0"}, filter.Apply(tags))
+
+	filter = &TagFilter{SemverOnly: true}
+	assert.Equal(t, []string{"v1.2.0", "1.3.0"}, filter.Apply(tags))
+
+	assert.Error(t, (&TagFilter{Include: []string{"[a-"}}).Validate())
+	assert.Error(t, (&TagFilter{Exclude: []string{"[a-"}}).Validate())
+
+	var nilOptions *RegistryOptions
+	assert.Nil(t, nilOptions.TagFilter())
+	options := MakeRegistryOptions(false, false, false, "", "", "", Generic).WithTagFilter(filter)
+	assert.Same(t, filter, options.TagFilter())
+	assert.Nil(t, nilOptions.RepositoryTagFilter("app"))
+	releases := &TagFilter{SemverOnly: true}
+	options.WithRepositoryTagFilter("app", releases)
+	assert.Same(t, releases, options.RepositoryTagFilter("app"))
+	assert.Nil(t, options.RepositoryTagFilter("other"))
+}
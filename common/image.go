@@ -0,0 +1,132 @@
+package common
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	version "github.com/hashicorp/go-version"
+)
+
+// ImageInspection describes a manifest (or an index) in a repository and the image configuration it points to
+type ImageInspection struct {
+	Reference   string
+	MediaType   types.MediaType
+	Digest      string
+	Size        int64
+	RawManifest []byte
+	Manifest    *v1.Manifest      // nil for indexes and docker schema1 manifests
+	Index       *v1.IndexManifest // set only for indexes
+	Config      *v1.ConfigFile    // nil for indexes
+	Layers      []v1.Descriptor   // ordered from the base layer
+	Platforms   []PlatformImage   // the selected images of an index
+}
+
+// IsIndex reports whether the inspected manifest is a multi-platform index
+func (i *ImageInspection) IsIndex() bool {
+	return i.Index != nil
+}
+
+// TaggedImage is an image (or a multi-platform index) with all the tags pointing to it
+type TaggedImage struct {
+	Tags      []string
+	Digest    string // index digest for multi-platform images
+	Created   time.Time
+	Pushed    time.Time       // zero when the registry does not record push times
+	Platforms []PlatformImage // the selected images of an index
+}
+
+// PlatformImage is a single platform image of an index
+type PlatformImage struct {
+	Platform v1.Platform
+	Digest   string
+	Created  time.Time
+}
+
+// LatestTagsOption controls the selection of the latest images of a repository
+type LatestTagsOption struct {
+	Platform PlatformOption
+	// Strategy ranks the images, nil ranks by creation time and returns "latest" right away when depth is 1
+	Strategy LatestTagStrategy
+	// Filter skips tags before their manifests are fetched, in addition to the registry tag filter
+	Filter *TagFilter
+}
+
+// InspectImage fills the manifest, configuration and layers of an image inspection
+func InspectImage(image v1.Image, inspection *ImageInspection) error {
+	var err error
+	if inspection.Manifest, err = image.Manifest(); err != nil {
+		return err
+	}
+	if inspection.Config, err = image.ConfigFile(); err != nil {
+		return err
+	}
+	inspection.Layers = inspection.Manifest.Layers
+	return nil
+}
+
+// SortImageTags sorts multiple tags on a single image by version if possible otherwise sort by sematic version otherwise sort alphabetically
+func SortImageTags(tags []string) {
+	if len(tags) == 1 {
+		return
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		//latest always comes first
+		if tags[i] == "latest" {
+			return true
+		}
+		if tags[j] == "latest" {
+			return false
+		}
+		//try to parse the version
+		vi, erri := version.NewVersion(tags[i])
+		vj, errj := version.NewVersion(tags[j])
+		if erri == nil && errj == nil {
+			return vj.LessThan(vi)
+		}
+		if erri != nil && errj != nil {
+			//no version so sort alphabetically
+			return strings.ToLower(tags[i]) > strings.ToLower(tags[j])
+		}
+		//advance versions over non-versions
+		if erri == nil {
+			return true
+		}
+		return false
+	})
+}
+
+// PlatformImages returns the images of index matching platform with their creation time
+// unless all platforms are requested only the first matching image is returned
+func PlatformImages(index v1.ImageIndex, platform PlatformOption) ([]PlatformImage, error) {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	platforms := []PlatformImage{}
+	for _, manifest := range indexManifest.Manifests {
+		if !manifest.MediaType.IsImage() || !platform.Matches(manifest.Platform) {
+			continue
+		}
+		image, err := index.Image(manifest.Digest)
+		if err != nil {
+			return nil, err
+		}
+		cf, err := image.ConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		//the platform of an index child is optional, all the platforms include children without one
+		platformImage := PlatformImage{Digest: manifest.Digest.String(), Created: cf.Created.Time}
+		if manifest.Platform != nil {
+			platformImage.Platform = *manifest.Platform
+		}
+		platforms = append(platforms, platformImage)
+		if !platform.All {
+			break
+		}
+	}
+	return platforms, nil
+}
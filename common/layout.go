@@ -0,0 +1,28 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// RefNameAnnotation names the manifests of an OCI image layout index.json, registryx writes "<repository>:<tag>"
+	RefNameAnnotation = "org.opencontainers.image.ref.name"
+	// ImageNameAnnotation is the full source reference of a layout manifest (as written by containerd)
+	ImageNameAnnotation = "io.containerd.image.name"
+)
+
+// LayoutRefName is the ref.name annotation of a tag of repoName in an OCI image layout
+func LayoutRefName(repoName, tag string) string {
+	return fmt.Sprintf("%s:%s", repoName, tag)
+}
+
+// ParseLayoutRefName splits a "<repository>:<tag>" ref.name annotation,
+// a plain tag (as written by most tools for single repository layouts) has an empty repository
+func ParseLayoutRefName(refName string) (repoName string, tag string) {
+	i := strings.LastIndex(refName, ":")
+	if i < 0 || strings.Contains(refName[i:], "/") {
+		return "", refName
+	}
+	return refName[:i], refName[i+1:]
+}
@@ -0,0 +1,26 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLayoutRefName(t *testing.T) {
+	tests := []struct {
+		refName string
+		repo    string
+		tag     string
+	}{
+		{refName: LayoutRefName("team/app", "1.0"), repo: "team/app", tag: "1.0"},
+		{refName: "localhost:5000/app:latest", repo: "localhost:5000/app", tag: "latest"},
+		{refName: "1.0", repo: "", tag: "1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.refName, func(t *testing.T) {
+			repo, tag := ParseLayoutRefName(tt.refName)
+			assert.Equal(t, tt.repo, repo)
+			assert.Equal(t, tt.tag, tag)
+		})
+	}
+}
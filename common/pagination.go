@@ -24,12 +24,21 @@ func NoPaginationOption() PaginationOption {
 //TODO - get pagination info for request header instead of guessing next page existence by size
 func CalcNextV2Pagination(repos []string, size int) *PaginationOption {
 	//assume that if response repos use all the allowed size then probably there is another page
-	if len(repos) < size {
+	if size <= 0 || len(repos) < size {
 		return nil
 	}
 	return &PaginationOption{Cursor: repos[len(repos)-1], Size: size}
 }
 
+// NextV2Pagination returns the next page from the response Link header,
+// when the registry does not send the header the next page is guessed by the page size
+func NextV2Pagination(resp *http.Response, repos []string, size int) (*PaginationOption, error) {
+	if resp.Header.Get("Link") == "" {
+		return CalcNextV2Pagination(repos, size), nil
+	}
+	return GetNextV2Pagination(resp)
+}
+
 //GetNextV2Pagination from response header
 func GetNextV2Pagination(resp *http.Response) (*PaginationOption, error) {
 	link := resp.Header.Get("Link")
@@ -45,8 +54,11 @@ func GetNextV2Pagination(resp *http.Response) (*PaginationOption, error) {
 	if end == -1 {
 		return nil, fmt.Errorf("failed to parse link header: missing '>' in: %s", link)
 	}
-	link = link[1:end]
+	return ParseNextV2Pagination(link[1:end])
+}
 
+// ParseNextV2Pagination returns the page of a next page URL with the n and last params
+func ParseNextV2Pagination(link string) (*PaginationOption, error) {
 	linkURL, err := url.Parse(link)
 	if err != nil {
 		return nil, err
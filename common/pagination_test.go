@@ -60,3 +60,33 @@ func TestGetNextV2Pagination(t *testing.T) {
 	assert.EqualError(t, err, "last cursor is missing in next page header")
 
 }
+
+func TestNextV2Pagination(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	repos := []string{"first", "second", "last"}
+	nextPage, err := NextV2Pagination(resp, repos, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, &PaginationOption{Cursor: "last", Size: 3}, nextPage)
+
+	nextPage, err = NextV2Pagination(resp, repos, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+
+	nextPage, err = NextV2Pagination(resp, []string{}, 3)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+
+	resp.Header.Set("Link", `</v2/_catalog?last=second&n=2>; rel="next"`)
+	nextPage, err = NextV2Pagination(resp, repos, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, &PaginationOption{Cursor: "second", Size: 2}, nextPage)
+}
+
+func TestParseNextV2Pagination(t *testing.T) {
+	nextPage, err := ParseNextV2Pagination("https://registry.example.com/v2/my-repo/tags/list?last=v2&n=50")
+	assert.Nil(t, err)
+	assert.Equal(t, &PaginationOption{Cursor: "v2", Size: 50}, nextPage)
+
+	_, err = ParseNextV2Pagination("/v2/my-repo/tags/list?n=50")
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	DEFAULT_OS           = "linux"
+	DEFAULT_ARCHITECTURE = "amd64"
+	ALL_PLATFORMS        = "all"
+)
+
+// PlatformOption selects the images of a multi-platform index.
+// The zero value is the default platform (linux/amd64) which is applied only to indexes,
+// an explicit platform is also matched against single platform images
+type PlatformOption struct {
+	OS           string
+	Architecture string
+	Variant      string // empty matches any variant
+	All          bool
+}
+
+func AllPlatforms() PlatformOption {
+	return PlatformOption{All: true}
+}
+
+// ParsePlatformOption parses "os/arch[/variant]" (e.g "linux/arm64/v8") or "all"
+func ParsePlatformOption(platform string) (PlatformOption, error) {
+	if platform == "" {
+		return PlatformOption{}, nil
+	}
+	if strings.ToLower(platform) == ALL_PLATFORMS {
+		return AllPlatforms(), nil
+	}
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return PlatformOption{}, fmt.Errorf("invalid platform %s, expected os/arch[/variant] or %s", platform, ALL_PLATFORMS)
+	}
+	option := PlatformOption{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		option.Variant = parts[2]
+	}
+	return option, nil
+}
+
+func (p PlatformOption) IsDefault() bool {
+	return p == PlatformOption{}
+}
+
+// Matches reports whether platform is selected by the option
+func (p PlatformOption) Matches(platform *v1.Platform) bool {
+	if p.All {
+		return true
+	}
+	if platform == nil {
+		return false
+	}
+	os, architecture := p.OS, p.Architecture
+	if os == "" {
+		os = DEFAULT_OS
+	}
+	if architecture == "" {
+		architecture = DEFAULT_ARCHITECTURE
+	}
+	if platform.OS != os || platform.Architecture != architecture {
+		return false
+	}
+	return p.Variant == "" || platform.Variant == p.Variant
+}
+
+func (p PlatformOption) String() string {
+	if p.All {
+		return ALL_PLATFORMS
+	}
+	platform := v1.Platform{OS: p.OS, Architecture: p.Architecture, Variant: p.Variant}
+	if platform.OS == "" {
+		platform.OS = DEFAULT_OS
+	}
+	if platform.Architecture == "" {
+		platform.Architecture = DEFAULT_ARCHITECTURE
+	}
+	return platform.String()
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlatformOption(t *testing.T) {
+	option, err := ParsePlatformOption("")
+	assert.Nil(t, err)
+	assert.True(t, option.IsDefault())
+
+	option, err = ParsePlatformOption("ALL")
+	assert.Nil(t, err)
+	assert.Equal(t, AllPlatforms(), option)
+
+	option, err = ParsePlatformOption("linux/arm64/v8")
+	assert.Nil(t, err)
+	assert.Equal(t, PlatformOption{OS: "linux", Architecture: "arm64", Variant: "v8"}, option)
+	assert.Equal(t, "linux/arm64/v8", option.String())
+
+	for _, invalid := range []string{"linux", "linux/", "/amd64", "linux/arm/v7/extra"} {
+		_, err = ParsePlatformOption(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPlatformOptionMatches(t *testing.T) {
+	amd64 := &v1.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := &v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}
+	windows := &v1.Platform{OS: "windows", Architecture: "amd64"}
+
+	assert.True(t, PlatformOption{}.Matches(amd64))
+	assert.False(t, PlatformOption{}.Matches(arm64))
+	assert.False(t, PlatformOption{}.Matches(nil))
+	assert.Equal(t, "linux/amd64", PlatformOption{}.String())
+
+	assert.True(t, PlatformOption{OS: "linux", Architecture: "arm64"}.Matches(arm64))
+	assert.False(t, PlatformOption{OS: "linux", Architecture: "arm64", Variant: "v7"}.Matches(arm64))
+	assert.True(t, PlatformOption{OS: "windows", Architecture: "amd64"}.Matches(windows))
+	assert.False(t, PlatformOption{OS: "windows", Architecture: "amd64"}.Matches(amd64))
+
+	for _, platform := range []*v1.Platform{amd64, arm64, windows, nil} {
+		assert.True(t, AllPlatforms().Matches(platform))
+	}
+}
+
+func TestPlatformImagesWithoutPlatform(t *testing.T) {
+	amd64, err := random.Image(128, 1)
+	assert.Nil(t, err)
+	unknown, err := random.Image(128, 1)
+	assert.Nil(t, err)
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: unknown},
+		mutate.IndexAddendum{Add: amd64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+	)
+
+	platforms, err := PlatformImages(index, AllPlatforms())
+	assert.Nil(t, err)
+	assert.Len(t, platforms, 2)
+	assert.Equal(t, v1.Platform{}, platforms[0].Platform)
+	assert.Equal(t, "amd64", platforms[1].Platform.Architecture)
+
+	//children without a platform are not the default platform
+	platforms, err = PlatformImages(index, PlatformOption{})
+	assert.Nil(t, err)
+	assert.Len(t, platforms, 1)
+	assert.Equal(t, "amd64", platforms[0].Platform.Architecture)
+}
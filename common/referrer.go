@@ -0,0 +1,103 @@
+package common
+
+import (
+	"encoding/json"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	CosignSignatureArtifactType   = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	CosignSimpleSigningMediaType  = "application/vnd.dev.cosign.simplesigning.v1+json"
+	DSSEEnvelopeMediaType         = "application/vnd.dsse.envelope.v1+json"
+	InTotoMediaType               = "application/vnd.in-toto+json"
+	NotationSignatureArtifactType = "application/vnd.cncf.notary.signature"
+	SPDXMediaType                 = "application/spdx+json"
+	CycloneDXMediaType            = "application/vnd.cyclonedx+json"
+)
+
+// cosign tag based artifacts are stored as sha256-<hex>.<suffix> tags in the image repository
+const (
+	CosignSignatureTagSuffix   = ".sig"
+	CosignAttestationTagSuffix = ".att"
+	CosignSBOMTagSuffix        = ".sbom"
+)
+
+var CosignTagSuffixes = []string{CosignSignatureTagSuffix, CosignAttestationTagSuffix, CosignSBOMTagSuffix}
+
+// Referrer is an artifact (signature, SBOM, attestation...) attached to an image digest
+type Referrer struct {
+	Digest       string
+	MediaType    types.MediaType
+	ArtifactType string
+	Size         int64
+	Annotations  map[string]string
+	Tag          string // set for cosign tag based artifacts
+}
+
+func (r Referrer) IsSignature() bool {
+	return r.ArtifactType == CosignSignatureArtifactType || r.ArtifactType == NotationSignatureArtifactType || strings.HasSuffix(r.Tag, CosignSignatureTagSuffix)
+}
+
+func (r Referrer) IsSBOM() bool {
+	return r.ArtifactType == SPDXMediaType || r.ArtifactType == CycloneDXMediaType || strings.HasSuffix(r.Tag, CosignSBOMTagSuffix) ||
+		strings.Contains(r.ArtifactType, "spdx") || strings.Contains(r.ArtifactType, "cyclonedx")
+}
+
+func (r Referrer) IsAttestation() bool {
+	return r.ArtifactType == DSSEEnvelopeMediaType || r.ArtifactType == InTotoMediaType || strings.HasSuffix(r.Tag, CosignAttestationTagSuffix)
+}
+
+// CosignTag returns the cosign tag of an artifact attached to digest (e.g sha256-<hex>.sig)
+func CosignTag(digest, suffix string) string {
+	return strings.Replace(digest, ":", "-", 1) + suffix
+}
+
+// IsCosignTag reports whether tag is a cosign tag based artifact
+func IsCosignTag(tag string) bool {
+	if !strings.HasPrefix(tag, "sha256-") {
+		return false
+	}
+	for _, suffix := range CosignTagSuffixes {
+		if strings.HasSuffix(tag, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CosignReferrer describes the cosign artifact manifest stored in tag, artifacts without an artifact type
+// are typed as signatures for the .sig suffix or by their payload media type
+func CosignReferrer(desc v1.Descriptor, rawManifest []byte, tag string, suffix string) (*Referrer, error) {
+	referrer := &Referrer{
+		Digest:    desc.Digest.String(),
+		MediaType: desc.MediaType,
+		Size:      desc.Size,
+		Tag:       tag,
+	}
+	manifest := artifactManifest{}
+	if err := json.Unmarshal(rawManifest, &manifest); err != nil {
+		return nil, err
+	}
+	referrer.Annotations = manifest.Annotations
+	referrer.ArtifactType = manifest.ArtifactType
+	if referrer.ArtifactType == "" {
+		switch {
+		case suffix == CosignSignatureTagSuffix:
+			referrer.ArtifactType = CosignSignatureArtifactType
+		case len(manifest.Layers) > 0:
+			//attestations and SBOMs are typed by their payload
+			referrer.ArtifactType = string(manifest.Layers[0].MediaType)
+		}
+	}
+	return referrer, nil
+}
+
+// artifactManifest is the part of an OCI 1.1 manifest needed to type an artifact
+type artifactManifest struct {
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Layers       []v1.Descriptor   `json:"layers"`
+}
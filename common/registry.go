@@ -1,10 +1,13 @@
 package common
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
 const (
@@ -18,6 +21,8 @@ const (
 	Generic RegistryKind = ""
 	Harbor  RegistryKind = "harbor"
 	Quay    RegistryKind = "quay.io"
+	// OCILayout is a local OCI image layout directory, the registry name is the directory path
+	OCILayout RegistryKind = "oci-layout"
 )
 
 type RegistryOptions struct {
@@ -28,6 +33,11 @@ type RegistryOptions struct {
 	project         string       // empty
 	skipTLSVerify   bool         // default: do not skip
 	kind            RegistryKind //registry provider (e.g. harbor) default is "Generic"
+	// latest tag selection, nil keeps the default selection
+	latestTagStrategy    LatestTagStrategy
+	repositoryStrategies map[string]LatestTagStrategy
+	tagFilter            *TagFilter // nil keeps every tag
+	repositoryFilters    map[string]*TagFilter
 }
 
 func GetRegistryKind(kindStr string) (RegistryKind, error) {
@@ -36,6 +46,8 @@ func GetRegistryKind(kindStr string) (RegistryKind, error) {
 		return Harbor, nil
 	case Quay:
 		return Quay, nil
+	case OCILayout:
+		return OCILayout, nil
 	case Generic:
 		return Generic, nil
 	default:
@@ -79,6 +91,24 @@ func MakeRepoWithRegistry(repoName string, registry *name.Registry) (*name.Repos
 	return &repo, nil
 }
 
+// MakeReference builds a tag or a digest (e.g. "sha256:...") reference of repoName in registry
+func MakeReference(repoName, reference string, registry *name.Registry) (name.Reference, error) {
+	repo, err := MakeRepoWithRegistry(repoName, registry)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(reference, ":") {
+		if _, err := v1.NewHash(reference); err != nil {
+			return nil, err
+		}
+		return repo.Digest(reference), nil
+	}
+	if reference == "" {
+		return nil, fmt.Errorf("empty reference for %s", repo.String())
+	}
+	return repo.Tag(reference), nil
+}
+
 func (r *RegistryOptions) Kind() RegistryKind {
 	return r.kind
 }
@@ -107,6 +137,17 @@ func (r *RegistryOptions) SkipTLSVerify() bool {
 	return r.skipTLSVerify
 }
 
+// Transport returns the transport of the registry requests, TLS certificates are not verified with SkipTLSVerify
+func (r *RegistryOptions) Transport() http.RoundTripper {
+	if r == nil || !r.skipTLSVerify {
+		return http.DefaultTransport
+	}
+	//clone the default transport, setting the TLS config of http.DefaultTransport itself would skip verification in the whole process
+	skipVerifyTransport := http.DefaultTransport.(*http.Transport).Clone()
+	skipVerifyTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return skipVerifyTransport
+}
+
 func (r *RegistryOptions) WithInsecure(insecure bool) *RegistryOptions {
 	r.insecure = insecure
 	return r
@@ -116,3 +157,60 @@ func (r *RegistryOptions) WithSkipTLSVerify(skipTLSVerify bool) *RegistryOptions
 	r.skipTLSVerify = skipTLSVerify
 	return r
 }
+
+func (r *RegistryOptions) WithLatestTagStrategy(strategy LatestTagStrategy) *RegistryOptions {
+	r.latestTagStrategy = strategy
+	return r
+}
+
+// WithRepositoryLatestTagStrategy overrides the latest tag strategy of a single repository
+func (r *RegistryOptions) WithRepositoryLatestTagStrategy(repoName string, strategy LatestTagStrategy) *RegistryOptions {
+	if r.repositoryStrategies == nil {
+		r.repositoryStrategies = map[string]LatestTagStrategy{}
+	}
+	r.repositoryStrategies[repoName] = strategy
+	return r
+}
+
+// LatestTagStrategy returns the latest tag strategy of a repository, nil if none is configured
+func (r *RegistryOptions) LatestTagStrategy(repoName string) LatestTagStrategy {
+	if r == nil {
+		return nil
+	}
+	if strategy, ok := r.repositoryStrategies[repoName]; ok {
+		return strategy
+	}
+	return r.latestTagStrategy
+}
+
+// WithTagFilter filters the tags listed by the registry (and so the tags considered for the latest tags)
+func (r *RegistryOptions) WithTagFilter(filter *TagFilter) *RegistryOptions {
+	r.tagFilter = filter
+	return r
+}
+
+// TagFilter returns the tags filter of the registry, nil if none is configured
+func (r *RegistryOptions) TagFilter() *TagFilter {
+	if r == nil {
+		return nil
+	}
+	return r.tagFilter
+}
+
+// WithRepositoryTagFilter filters the tags of a single repository when the registry clients select its latest tag,
+// in addition to the registry tag filter
+func (r *RegistryOptions) WithRepositoryTagFilter(repoName string, filter *TagFilter) *RegistryOptions {
+	if r.repositoryFilters == nil {
+		r.repositoryFilters = map[string]*TagFilter{}
+	}
+	r.repositoryFilters[repoName] = filter
+	return r
+}
+
+// RepositoryTagFilter returns the tags filter of a repository, nil if none is configured
+func (r *RegistryOptions) RepositoryTagFilter(repoName string) *TagFilter {
+	if r == nil {
+		return nil
+	}
+	return r.repositoryFilters[repoName]
+}
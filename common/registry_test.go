@@ -1,6 +1,7 @@
 package common
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/name"
@@ -289,3 +290,48 @@ func TestMakeRepoWithRegistry_NilRegistry(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeReference(t *testing.T) {
+	registry, err := name.NewRegistry("myacr.azurecr.io")
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+	ref, err := MakeReference("team/myapp", "v1.2", &registry)
+	if err != nil {
+		t.Fatalf("MakeReference() error = %v", err)
+	}
+	if ref.String() != "myacr.azurecr.io/team/myapp:v1.2" {
+		t.Errorf("reference = %q", ref.String())
+	}
+	digest := "sha256:cc8567d70002e957612902a8e985ea129d831ebe04057d88fb644857caa45d11"
+	ref, err = MakeReference("team/myapp", digest, &registry)
+	if err != nil {
+		t.Fatalf("MakeReference() error = %v", err)
+	}
+	if ref.String() != "myacr.azurecr.io/team/myapp@"+digest {
+		t.Errorf("reference = %q", ref.String())
+	}
+	if _, err := MakeReference("team/myapp", "sha256:invalid", &registry); err == nil {
+		t.Errorf("expected an error for an invalid digest")
+	}
+	if _, err := MakeReference("team/myapp", "", &registry); err == nil {
+		t.Errorf("expected an error for an empty reference")
+	}
+}
+
+func TestRegistryOptionsTransport(t *testing.T) {
+	var nilOptions *RegistryOptions
+	if nilOptions.Transport() != http.DefaultTransport {
+		t.Errorf("nil options should use the default transport")
+	}
+	if MakeRegistryOptions(false, false, false, "", "", "", Generic).Transport() != http.DefaultTransport {
+		t.Errorf("options without SkipTLSVerify should use the default transport")
+	}
+	skipVerify, ok := MakeRegistryOptions(false, false, true, "", "", "", Generic).Transport().(*http.Transport)
+	if !ok || !skipVerify.TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("options with SkipTLSVerify should skip the certificate verification")
+	}
+	if http.DefaultTransport.(*http.Transport).TLSClientConfig != nil && http.DefaultTransport.(*http.Transport).TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("the default transport should not be modified")
+	}
+}
@@ -0,0 +1,229 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// LatestTagStrategy ranks the images of a repository to select the latest ones.
+// Image strategies rank by data of the images (every accepted tag is fetched),
+// tag strategies rank by the tag names (only the newest tags are fetched)
+type LatestTagStrategy interface {
+	// Accepts reports whether the strategy can rank a tag, other tags are ignored
+	Accepts(tag string) bool
+	// RanksTags reports whether the strategy ranks tag names rather than images
+	RanksTags() bool
+	// NewerTag reports whether accepted tag a is newer than accepted tag b (tag strategies only)
+	NewerTag(a, b string) bool
+	// NewerImage reports whether image a is newer than image b
+	NewerImage(a, b *TaggedImage) bool
+}
+
+// imageStrategy ranks images by their data
+type imageStrategy struct {
+	newer      func(a, b *TaggedImage) bool
+	byPushTime bool
+}
+
+func (imageStrategy) Accepts(string) bool                 { return true }
+func (imageStrategy) RanksTags() bool                     { return false }
+func (imageStrategy) NewerTag(string, string) bool        { return false }
+func (s imageStrategy) NewerImage(a, b *TaggedImage) bool { return s.newer(a, b) }
+
+// tagStrategy ranks images by their newest accepted tag
+type tagStrategy struct {
+	accepts func(tag string) bool
+	newer   func(a, b string) bool
+}
+
+func (s tagStrategy) Accepts(tag string) bool   { return s.accepts(tag) }
+func (tagStrategy) RanksTags() bool             { return true }
+func (s tagStrategy) NewerTag(a, b string) bool { return s.newer(a, b) }
+
+func (s tagStrategy) NewerImage(a, b *TaggedImage) bool {
+	newestA, okA := s.newest(a.Tags)
+	newestB, okB := s.newest(b.Tags)
+	if !okA || !okB {
+		return okA
+	}
+	return s.newer(newestA, newestB)
+}
+
+func (s tagStrategy) newest(tags []string) (string, bool) {
+	newest, found := "", false
+	for _, tag := range tags {
+		if s.accepts(tag) && (!found || s.newer(tag, newest)) {
+			newest, found = tag, true
+		}
+	}
+	return newest, found
+}
+
+// CreatedStrategy ranks images by the creation time of their configuration
+func CreatedStrategy() LatestTagStrategy {
+	return &imageStrategy{newer: func(a, b *TaggedImage) bool {
+		return a.Created.After(b.Created)
+	}}
+}
+
+// PushedStrategy ranks images by the time they were pushed to the registry,
+// images without a push time (registries that do not record it) are ranked by creation time
+func PushedStrategy() LatestTagStrategy {
+	return &imageStrategy{newer: func(a, b *TaggedImage) bool {
+		if a.Pushed.IsZero() || b.Pushed.IsZero() {
+			return a.Created.After(b.Created)
+		}
+		return a.Pushed.After(b.Pushed)
+	}, byPushTime: true}
+}
+
+// RanksByPushTime reports whether a strategy ranks images by their push time (PushedStrategy) or by their tags,
+// registries recording the push time can rank images by such strategies without fetching the image configs
+func RanksByPushTime(strategy LatestTagStrategy) bool {
+	if strategy.RanksTags() {
+		return true
+	}
+	s, ok := strategy.(*imageStrategy)
+	return ok && s.byPushTime
+}
+
+var strictSemverRegex = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// SemverStrategy ranks tags that are full semantic versions (MAJOR.MINOR.PATCH with an optional "v" prefix)
+func SemverStrategy() LatestTagStrategy {
+	return semverStrategy(func(tag string) (*semver.Version, bool) {
+		if !strictSemverRegex.MatchString(tag) {
+			return nil, false
+		}
+		version, err := semver.NewVersion(tag)
+		return version, err == nil
+	})
+}
+
+// SemverConstraintStrategy ranks the version tags satisfying a constraint (e.g. "~1.4", ">= 2.0, < 3")
+func SemverConstraintStrategy(constraint string) (LatestTagStrategy, error) {
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid semver constraint %q: %w", constraint, err)
+	}
+	return semverStrategy(func(tag string) (*semver.Version, bool) {
+		version, err := semver.NewVersion(tag)
+		if err != nil {
+			return nil, false
+		}
+		return version, constraints.Check(version)
+	}), nil
+}
+
+func semverStrategy(parse func(tag string) (*semver.Version, bool)) LatestTagStrategy {
+	return &tagStrategy{
+		accepts: func(tag string) bool {
+			_, ok := parse(tag)
+			return ok
+		},
+		newer: func(a, b string) bool {
+			versionA, _ := parse(a)
+			versionB, _ := parse(b)
+			if cmp := versionA.Compare(versionB); cmp != 0 {
+				return cmp > 0
+			}
+			//same version with different notations (e.g v1.0.0 and 1.0.0)
+			return a > b
+		},
+	}
+}
+
+// DefaultCalverLayouts are the date layouts of calendar version tags (e.g 2024.05.13, 2024-05-13, 20240513, 2024.05)
+var DefaultCalverLayouts = []string{"2006.01.02", "2006-01-02", "20060102", "2006.01", "2006-01"}
+
+var calverMicroRegex = regexp.MustCompile(`^[.\-_+](\d+)$`)
+
+// CalverStrategy ranks calendar version tags, a date in one of layouts (DefaultCalverLayouts by default)
+// optionally followed by a build counter (e.g 2024.05.13.2 or 2024.05-3)
+func CalverStrategy(layouts ...string) LatestTagStrategy {
+	if len(layouts) == 0 {
+		layouts = DefaultCalverLayouts
+	}
+	parse := func(tag string) (time.Time, uint64, bool) {
+		tag = strings.TrimPrefix(tag, "v")
+		for _, layout := range layouts {
+			if len(tag) < len(layout) {
+				continue
+			}
+			date, err := time.Parse(layout, tag[:len(layout)])
+			if err != nil {
+				continue
+			}
+			if rest := tag[len(layout):]; rest != "" {
+				match := calverMicroRegex.FindStringSubmatch(rest)
+				if match == nil {
+					continue
+				}
+				micro, err := strconv.ParseUint(match[1], 10, 64)
+				if err != nil {
+					continue
+				}
+				return date, micro, true
+			}
+			return date, 0, true
+		}
+		return time.Time{}, 0, false
+	}
+	return &tagStrategy{
+		accepts: func(tag string) bool {
+			_, _, ok := parse(tag)
+			return ok
+		},
+		newer: func(a, b string) bool {
+			dateA, microA, _ := parse(a)
+			dateB, microB, _ := parse(b)
+			if !dateA.Equal(dateB) {
+				return dateA.After(dateB)
+			}
+			return microA > microB
+		},
+	}
+}
+
+// BuildNumberStrategy ranks tags by the build number captured by pattern,
+// the number is the "build" named group if present otherwise the first group (e.g `^main-(\d+)$`)
+func BuildNumberStrategy(pattern string) (LatestTagStrategy, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid build number pattern %q: %w", pattern, err)
+	}
+	if regex.NumSubexp() == 0 {
+		return nil, fmt.Errorf("build number pattern %q has no capture group", pattern)
+	}
+	group := 1
+	if named := regex.SubexpIndex("build"); named > 0 {
+		group = named
+	}
+	parse := func(tag string) (uint64, bool) {
+		match := regex.FindStringSubmatch(tag)
+		if match == nil {
+			return 0, false
+		}
+		number, err := strconv.ParseUint(match[group], 10, 64)
+		return number, err == nil
+	}
+	return &tagStrategy{
+		accepts: func(tag string) bool {
+			_, ok := parse(tag)
+			return ok
+		},
+		newer: func(a, b string) bool {
+			numberA, _ := parse(a)
+			numberB, _ := parse(b)
+			if numberA != numberB {
+				return numberA > numberB
+			}
+			return a > b
+		},
+	}, nil
+}
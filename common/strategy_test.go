@@ -0,0 +1,84 @@
+package common
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rankTags returns the accepted tags from newest to oldest
+func rankTags(strategy LatestTagStrategy, tags []string) []string {
+	ranked := []string{}
+	for _, tag := range tags {
+		if strategy.Accepts(tag) {
+			ranked = append(ranked, tag)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return strategy.NewerTag(ranked[i], ranked[j])
+	})
+	return ranked
+}
+
+func TestTagStrategies(t *testing.T) {
+	tags := []string{"latest", "v1.2.0", "1.10.0", "1.4.3", "1.4.12", "1.5.0-rc.1", "1.4", "sha-abc", "pr-123",
+		"2024.05.13", "2024.05.13.2", "2023-12-01", "20240601", "main-9", "main-10", "main-x"}
+
+	assert.Equal(t, []string{"1.10.0", "1.5.0-rc.1", "1.4.12", "1.4.3", "v1.2.0"}, rankTags(SemverStrategy(), tags))
+
+	constraint, err := SemverConstraintStrategy("~1.4")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.4.12", "1.4.3", "1.4"}, rankTags(constraint, tags))
+	_, err = SemverConstraintStrategy("not a constraint")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"20240601", "2024.05.13.2", "2024.05.13", "2023-12-01"}, rankTags(CalverStrategy(), tags))
+	assert.Equal(t, []string{"2023-12-01"}, rankTags(CalverStrategy("2006-01-02"), tags))
+
+	build, err := BuildNumberStrategy(`^main-(\d+)$`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"main-10", "main-9"}, rankTags(build, tags))
+	named, err := BuildNumberStrategy(`^(main|pr)-(?P<build>\d+)$`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pr-123", "main-10", "main-9"}, rankTags(named, tags))
+	_, err = BuildNumberStrategy(`^main-\d+$`)
+	assert.Error(t, err)
+
+	//images are ranked by their newest accepted tag
+	semver := SemverStrategy()
+	assert.True(t, semver.RanksTags())
+	assert.True(t, semver.NewerImage(&TaggedImage{Tags: []string{"latest", "2.0.0"}}, &TaggedImage{Tags: []string{"1.0.0", "1.9.0"}}))
+	assert.True(t, semver.NewerImage(&TaggedImage{Tags: []string{"1.0.0"}}, &TaggedImage{Tags: []string{"latest"}}))
+}
+
+func TestImageStrategies(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	older := &TaggedImage{Created: base.Add(time.Hour), Pushed: base.Add(time.Hour)}
+	rebuilt := &TaggedImage{Created: base, Pushed: base.Add(2 * time.Hour)}
+
+	assert.False(t, CreatedStrategy().RanksTags())
+	assert.True(t, CreatedStrategy().Accepts("anything"))
+	assert.True(t, CreatedStrategy().NewerImage(older, rebuilt))
+	assert.True(t, PushedStrategy().NewerImage(rebuilt, older))
+	//no push time falls back to the creation time
+	assert.True(t, PushedStrategy().NewerImage(&TaggedImage{Created: base.Add(time.Hour)}, &TaggedImage{Created: base}))
+
+	assert.True(t, RanksByPushTime(PushedStrategy()))
+	assert.True(t, RanksByPushTime(SemverStrategy()))
+	assert.False(t, RanksByPushTime(CreatedStrategy()))
+}
+
+func TestLatestTagStrategyOptions(t *testing.T) {
+	var nilOptions *RegistryOptions
+	assert.Nil(t, nilOptions.LatestTagStrategy("repo"))
+
+	options := MakeRegistryOptions(false, false, false, "", "", "", Generic)
+	assert.Nil(t, options.LatestTagStrategy("repo"))
+	semver := SemverStrategy()
+	calver := CalverStrategy()
+	options.WithLatestTagStrategy(semver).WithRepositoryLatestTagStrategy("nightly", calver)
+	assert.Same(t, semver, options.LatestTagStrategy("repo"))
+	assert.Same(t, calver, options.LatestTagStrategy("nightly"))
+}
@@ -0,0 +1,117 @@
+package common
+
+import (
+	"strings"
+	"time"
+)
+
+type Severity string
+
+const (
+	SeverityCritical   Severity = "Critical"
+	SeverityHigh       Severity = "High"
+	SeverityMedium     Severity = "Medium"
+	SeverityLow        Severity = "Low"
+	SeverityNegligible Severity = "Negligible"
+	SeverityUnknown    Severity = "Unknown"
+)
+
+// Severities are ordered from the most to the least severe
+var Severities = []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityNegligible, SeverityUnknown}
+
+// ParseSeverity normalizes a scanner severity (e.g "CRITICAL", "high", "Defcon1"), unknown values are SeverityUnknown
+func ParseSeverity(severity string) Severity {
+	switch strings.ToLower(severity) {
+	case "critical", "defcon1":
+		return SeverityCritical
+	case "high":
+		return SeverityHigh
+	case "medium", "moderate":
+		return SeverityMedium
+	case "low":
+		return SeverityLow
+	case "negligible", "none", "info", "informational":
+		return SeverityNegligible
+	default:
+		return SeverityUnknown
+	}
+}
+
+// rank is the position of s in Severities, lower is more severe
+func (s Severity) rank() int {
+	for i, severity := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// AtLeast reports whether s is as severe as or more severe than other
+func (s Severity) AtLeast(other Severity) bool {
+	return s.rank() <= other.rank()
+}
+
+// ScanStatus is the state of the registry scan of an image
+type ScanStatus string
+
+const (
+	ScanStatusNotScanned  ScanStatus = "not scanned"
+	ScanStatusQueued      ScanStatus = "queued"
+	ScanStatusRunning     ScanStatus = "running"
+	ScanStatusScanned     ScanStatus = "scanned"
+	ScanStatusFailed      ScanStatus = "failed"
+	ScanStatusUnsupported ScanStatus = "unsupported"
+)
+
+// Done reports whether the scan is over, successfully or not
+func (s ScanStatus) Done() bool {
+	return s == ScanStatusScanned || s == ScanStatusFailed || s == ScanStatusUnsupported
+}
+
+// Vulnerability is a vulnerable package found by a registry scanner
+type Vulnerability struct {
+	ID          string // CVE or advisory identifier
+	Package     string
+	Version     string
+	FixVersion  string // empty if there is no fix
+	Severity    Severity
+	CVSSScore   float64 // 0 if unknown
+	Description string
+	Links       []string
+}
+
+// VulnerabilityReport is the provider neutral result of a registry scan of an image
+type VulnerabilityReport struct {
+	Digest          string
+	Status          ScanStatus
+	Scanner         string // name and version of the scanner
+	GeneratedAt     time.Time
+	Vulnerabilities []Vulnerability
+}
+
+// Summary counts the vulnerabilities by severity
+func (r *VulnerabilityReport) Summary() map[Severity]int {
+	summary := map[Severity]int{}
+	for _, vulnerability := range r.Vulnerabilities {
+		summary[vulnerability.Severity]++
+	}
+	return summary
+}
+
+// Fixable returns the vulnerabilities with a fix version
+func (r *VulnerabilityReport) Fixable() []Vulnerability {
+	fixable := []Vulnerability{}
+	for _, vulnerability := range r.Vulnerabilities {
+		if vulnerability.FixVersion != "" {
+			fixable = append(fixable, vulnerability)
+		}
+	}
+	return fixable
+}
+
+// IsFresh reports whether the report is a completed scan generated less than maxAge ago,
+// a fresh report can be used instead of scanning the image again
+func (r *VulnerabilityReport) IsFresh(maxAge time.Duration) bool {
+	return r.Status == ScanStatusScanned && !r.GeneratedAt.IsZero() && time.Since(r.GeneratedAt) < maxAge
+}
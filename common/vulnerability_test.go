@@ -0,0 +1,33 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSeverity(t *testing.T) {
+	assert.Equal(t, SeverityCritical, ParseSeverity("CRITICAL"))
+	assert.Equal(t, SeverityCritical, ParseSeverity("Defcon1"))
+	assert.Equal(t, SeverityMedium, ParseSeverity("moderate"))
+	assert.Equal(t, SeverityNegligible, ParseSeverity("None"))
+	assert.Equal(t, SeverityUnknown, ParseSeverity("whatever"))
+
+	assert.True(t, SeverityCritical.AtLeast(SeverityHigh))
+	assert.True(t, SeverityHigh.AtLeast(SeverityHigh))
+	assert.False(t, SeverityLow.AtLeast(SeverityMedium))
+	assert.False(t, Severity("").AtLeast(SeverityUnknown))
+}
+
+func TestVulnerabilityReportIsFresh(t *testing.T) {
+	report := VulnerabilityReport{Status: ScanStatusScanned, GeneratedAt: time.Now().Add(-time.Hour)}
+	assert.True(t, report.IsFresh(2*time.Hour))
+	assert.False(t, report.IsFresh(time.Minute))
+	report.Status = ScanStatusRunning
+	assert.False(t, report.IsFresh(2*time.Hour))
+	assert.False(t, (&VulnerabilityReport{Status: ScanStatusScanned}).IsFresh(time.Hour))
+
+	assert.True(t, ScanStatusUnsupported.Done())
+	assert.False(t, ScanStatusQueued.Done())
+}
@@ -0,0 +1,79 @@
+package export
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// ExportDockerArchive exports images to a `docker save` compatible tarball at path.
+// The blobs are staged in the OCI image layout at cacheDir, which keeps them (and the partial downloads) for the next exports,
+// a temporary layout is used when cacheDir is empty
+func ExportDockerArchive(ctx context.Context, path string, cacheDir string, images []Image, opts ExportOption) ([]ExportResult, error) {
+	if cacheDir == "" {
+		tmp, err := os.MkdirTemp("", "registryx-export-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		cacheDir = tmp
+	}
+	if opts.Platform == nil {
+		opts.Platform = &common.PlatformOption{}
+	}
+	exporter, err := NewExporter(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	results, err := exporter.exportAll(ctx, images, opts)
+	if err != nil {
+		return results, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return results, err
+	}
+	defer file.Close()
+	if err := exporter.WriteDockerArchive(file, results...); err != nil {
+		return results, err
+	}
+	return results, file.Close()
+}
+
+// WriteDockerArchive writes exported images from the layout to a `docker save` compatible tarball.
+// An image is tagged with its source tag when it is the only image of its export, the images of a multi-platform export are untagged
+func (e *Exporter) WriteDockerArchive(w io.Writer, results ...ExportResult) error {
+	images := map[string]v1.Image{}
+	refToImage := map[name.Reference]v1.Image{}
+	for _, result := range results {
+		ref, err := name.ParseReference(result.Reference)
+		if err != nil {
+			return err
+		}
+		for _, platformImage := range result.Images {
+			image, ok := images[platformImage.Digest]
+			if !ok {
+				hash, err := v1.NewHash(platformImage.Digest)
+				if err != nil {
+					return err
+				}
+				if image, err = e.layout.Image(hash); err != nil {
+					return err
+				}
+				//the same image instance is written once for all its tags
+				images[platformImage.Digest] = image
+			}
+			if tag, ok := ref.(name.Tag); ok && len(result.Images) == 1 {
+				refToImage[tag] = image
+				continue
+			}
+			refToImage[ref.Context().Digest(platformImage.Digest)] = image
+		}
+	}
+	return tarball.MultiRefWrite(refToImage, w)
+}
@@ -0,0 +1,209 @@
+package export
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const partialSuffix = ".partial"
+
+var (
+	errDigestMismatch   = errors.New("digest mismatch")
+	errRangeUnsatisfied = errors.New("range not satisfiable")
+)
+
+// blobFetcher downloads the blobs of a repository into a layout, resuming the partial downloads with range requests
+type blobFetcher struct {
+	ctx    context.Context
+	client *http.Client // nil when the blobs are downloaded by remote
+	repo   name.Repository
+	// options of the remote downloads
+	options []remote.Option
+}
+
+// newBlobFetcher downloads with the registry credentials and transport. The caller options cannot be inspected,
+// so with callerOptions the blobs are downloaded by remote, which applies them, and the partial downloads are not resumed
+func newBlobFetcher(ctx context.Context, registry interfaces.IRegistry, repo name.Repository, callerOptions []remote.Option) (*blobFetcher, error) {
+	if len(callerOptions) > 0 {
+		return &blobFetcher{ctx: ctx, repo: repo, options: append(registries.RemoteOptions(ctx, registry), callerOptions...)}, nil
+	}
+	rt, err := transport.NewWithContext(ctx, repo.Registry, registries.Authenticator(registry), registries.Transport(registry), []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, err
+	}
+	return &blobFetcher{ctx: ctx, client: &http.Client{Transport: rt}, repo: repo}, nil
+}
+
+// fetch writes blob to the layout unless it is already there and returns the number of downloaded bytes.
+// The blob is downloaded to a partial file which is renamed once its digest is verified,
+// a failed download keeps the partial file for the next attempt
+func (f *blobFetcher) fetch(path layout.Path, blob v1.Descriptor) (int64, error) {
+	blobPath := filepath.Join(string(path), "blobs", blob.Digest.Algorithm, blob.Digest.Hex)
+	if info, err := os.Stat(blobPath); err == nil && info.Size() == blob.Size {
+		verified, err := verifyFile(blobPath, blob.Digest)
+		if err != nil {
+			return 0, err
+		}
+		if verified {
+			return 0, nil
+		}
+		//a corrupted blob is downloaded again
+	}
+	if err := os.MkdirAll(filepath.Dir(blobPath), os.ModePerm); err != nil {
+		return 0, err
+	}
+	partialPath := blobPath + partialSuffix
+	downloaded, resumed, err := f.download(partialPath, blob)
+	if resumed && (errors.Is(err, errDigestMismatch) || errors.Is(err, errRangeUnsatisfied)) {
+		//the partial file was corrupted, download the whole blob again
+		var retried int64
+		retried, _, err = f.download(partialPath, blob)
+		downloaded += retried
+	}
+	if err != nil {
+		return downloaded, err
+	}
+	return downloaded, os.Rename(partialPath, blobPath)
+}
+
+// download completes the partial file of blob and verifies it, a mismatching file is removed
+func (f *blobFetcher) download(partialPath string, blob v1.Descriptor) (downloaded int64, resumed bool, err error) {
+	hasher, err := newHasher(blob.Digest)
+	if err != nil {
+		return 0, false, err
+	}
+	file, err := os.OpenFile(partialPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+	offset, err := io.Copy(hasher, file)
+	if err != nil {
+		return 0, false, err
+	}
+	if offset >= blob.Size {
+		//nothing left to resume
+		if err := truncate(file); err != nil {
+			return 0, false, err
+		}
+		hasher.Reset()
+		offset = 0
+	}
+
+	res, err := f.get(blob, offset)
+	if err != nil {
+		return 0, false, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		resumed = true
+	case http.StatusOK:
+		//the registry does not support ranges, start over
+		if offset > 0 {
+			if err := truncate(file); err != nil {
+				return 0, false, err
+			}
+			hasher.Reset()
+			offset = 0
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset > 0 {
+			if err := truncate(file); err != nil {
+				return 0, false, err
+			}
+			return 0, true, fmt.Errorf("%w: %s from %d", errRangeUnsatisfied, blob.Digest.String(), offset)
+		}
+		return 0, false, transport.CheckError(res, http.StatusOK)
+	default:
+		return 0, false, transport.CheckError(res, http.StatusOK, http.StatusPartialContent)
+	}
+
+	//read one more byte than expected to detect oversized blobs
+	downloaded, err = io.Copy(io.MultiWriter(file, hasher), io.LimitReader(res.Body, blob.Size-offset+1))
+	if err != nil {
+		return downloaded, resumed, err
+	}
+	if offset+downloaded != blob.Size || fmt.Sprintf("%x", hasher.Sum(nil)) != blob.Digest.Hex {
+		file.Close()
+		if err := os.Remove(partialPath); err != nil {
+			return downloaded, resumed, err
+		}
+		return downloaded, resumed, fmt.Errorf("%w: expected %s with %d bytes", errDigestMismatch, blob.Digest.String(), blob.Size)
+	}
+	return downloaded, resumed, nil
+}
+
+// get requests blob from offset, the whole blob is returned with a 200 status when the range is not supported
+func (f *blobFetcher) get(blob v1.Descriptor, offset int64) (*http.Response, error) {
+	if f.client == nil {
+		layer, err := remote.Layer(f.repo.Digest(blob.Digest.String()), f.options...)
+		if err != nil {
+			return nil, err
+		}
+		body, err := layer.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+	}
+	uri := fmt.Sprintf("%s://%s/v2/%s/blobs/%s", f.repo.Registry.Scheme(), f.repo.RegistryStr(), f.repo.RepositoryStr(), blob.Digest.String())
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, blob.Size-1))
+	}
+	return f.client.Do(req)
+}
+
+// verifyFile reports whether the content of path matches digest
+func verifyFile(path string, digest v1.Hash) (bool, error) {
+	hasher, err := newHasher(digest)
+	if err != nil {
+		return false, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return false, err
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)) == digest.Hex, nil
+}
+
+func newHasher(digest v1.Hash) (hash.Hash, error) {
+	switch digest.Algorithm {
+	case "sha256":
+		return crypto.SHA256.New(), nil
+	case "sha512":
+		return crypto.SHA512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported digest algorithm %s", digest.Algorithm)
+}
+
+func truncate(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.Seek(0, io.SeekStart)
+	return err
+}
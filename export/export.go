@@ -0,0 +1,352 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const defaultExportJobs = 4
+
+// Image is a tag or a digest of a registry repository to export
+type Image struct {
+	Registry   interfaces.IRegistry
+	Repository string
+	Reference  string
+}
+
+// ExportOption controls the export of an image
+type ExportOption struct {
+	// Platform selects the images of an index, nil exports the whole index
+	// (docker archives hold single platform images, nil selects the default platform)
+	Platform *common.PlatformOption
+	// Jobs is the number of concurrent blob downloads of an image
+	Jobs int
+	// Options are appended to the options built from the registry credentials
+	Options []remote.Option
+}
+
+// ExportResult describes an exported image
+type ExportResult struct {
+	Reference string // the source reference
+	Digest    string // digest of the source manifest (index digest for multi-platform images)
+	// Images are the exported single platform images, an exported index has all its platforms
+	Images []common.PlatformImage
+	// Manifests are the descriptors added to the layout index.json
+	Manifests []v1.Descriptor
+	// Downloaded is the number of bytes fetched from the registry, blobs already in the layout are not downloaded
+	Downloaded int64
+}
+
+// Exporter writes images to an OCI image layout directory.
+// Blobs are shared by all the exported images, existing blobs are not downloaded again
+// and interrupted downloads resume from the partial files left in the layout
+type Exporter struct {
+	layout layout.Path
+	// indexLock serializes the index.json updates
+	indexLock sync.Mutex
+	// blobLocks prevents concurrent exports from downloading the same blob
+	blobLocks sync.Map
+}
+
+// NewExporter opens the OCI image layout at dir, the layout is created if dir does not hold one
+func NewExporter(dir string) (*Exporter, error) {
+	path, err := layout.FromPath(dir)
+	if err != nil {
+		if _, statErr := os.Stat(dir); statErr != nil && !os.IsNotExist(statErr) {
+			return nil, statErr
+		}
+		if path, err = layout.Write(dir, empty.Index); err != nil {
+			return nil, err
+		}
+	}
+	return &Exporter{layout: path}, nil
+}
+
+// Layout is the OCI image layout the images are exported to
+func (e *Exporter) Layout() layout.Path {
+	return e.layout
+}
+
+// ExportLayout exports images to the OCI image layout at dir
+func ExportLayout(ctx context.Context, dir string, images []Image, opts ExportOption) ([]ExportResult, error) {
+	exporter, err := NewExporter(dir)
+	if err != nil {
+		return nil, err
+	}
+	return exporter.exportAll(ctx, images, opts)
+}
+
+// exportAll exports images in order and stops at the first failure
+func (e *Exporter) exportAll(ctx context.Context, images []Image, opts ExportOption) ([]ExportResult, error) {
+	results := make([]ExportResult, 0, len(images))
+	for _, image := range images {
+		result, err := e.Export(ctx, image, opts)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// Export writes an image (or the selected platforms of an index) to the layout and adds it to index.json,
+// tagged images are annotated with their "<repository>:<tag>" ref.name replacing any previous export of the tag
+func (e *Exporter) Export(ctx context.Context, image Image, opts ExportOption) (*ExportResult, error) {
+	ref, err := common.MakeReference(image.Repository, image.Reference, image.Registry.GetRegistry())
+	if err != nil {
+		return nil, err
+	}
+	options := append(registries.RemoteOptions(ctx, image.Registry), opts.Options...)
+	desc, err := remote.Get(ref, options...)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := newBlobFetcher(ctx, image.Registry, ref.Context(), opts.Options)
+	if err != nil {
+		return nil, err
+	}
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = defaultExportJobs
+	}
+	w := &imageWriter{exporter: e, fetcher: fetcher, repo: ref.Context(), jobs: jobs, options: options}
+	result := &ExportResult{Reference: ref.String(), Digest: desc.Digest.String()}
+
+	var manifests []v1.Descriptor
+	if desc.MediaType.IsIndex() && opts.Platform == nil {
+		if result.Images, err = w.writeIndex(desc.Digest, desc.Manifest); err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, desc.Descriptor)
+	} else {
+		platform := common.PlatformOption{}
+		if opts.Platform != nil {
+			platform = *opts.Platform
+		}
+		images, err := selectImages(ref.Context(), desc, platform, options...)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			if err := w.writeImage(image.Digest, image.Manifest); err != nil {
+				return nil, err
+			}
+			result.Images = append(result.Images, common.PlatformImage{Platform: platformOf(image.Descriptor), Digest: image.Digest.String()})
+			manifests = append(manifests, image.Descriptor)
+		}
+	}
+	result.Downloaded = w.downloaded
+	if result.Manifests, err = e.addManifests(ref, manifests); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// addManifests adds the exported manifests to index.json, annotated with the source reference
+func (e *Exporter) addManifests(ref name.Reference, manifests []v1.Descriptor) ([]v1.Descriptor, error) {
+	e.indexLock.Lock()
+	defer e.indexLock.Unlock()
+	annotations := map[string]string{common.ImageNameAnnotation: ref.Name()}
+	if tag, ok := ref.(name.Tag); ok {
+		annotations[common.RefNameAnnotation] = common.LayoutRefName(tag.RepositoryStr(), tag.TagStr())
+		if err := e.layout.RemoveDescriptors(match.Annotation(common.RefNameAnnotation, annotations[common.RefNameAnnotation])); err != nil {
+			return nil, err
+		}
+	}
+	added := make([]v1.Descriptor, 0, len(manifests))
+	for _, manifest := range manifests {
+		descriptor := v1.Descriptor{
+			MediaType:   manifest.MediaType,
+			Size:        manifest.Size,
+			Digest:      manifest.Digest,
+			Platform:    manifest.Platform,
+			Annotations: annotations,
+		}
+		if _, ok := ref.(name.Digest); ok {
+			//an untagged export is replaced by digest
+			if err := e.layout.RemoveDescriptors(untagged(manifest.Digest)); err != nil {
+				return nil, err
+			}
+		}
+		if err := e.layout.AppendDescriptor(descriptor); err != nil {
+			return nil, err
+		}
+		added = append(added, descriptor)
+	}
+	return added, nil
+}
+
+// untagged matches the index.json manifests of digest without a ref.name
+func untagged(digest v1.Hash) match.Matcher {
+	return func(desc v1.Descriptor) bool {
+		_, tagged := desc.Annotations[common.RefNameAnnotation]
+		return desc.Digest == digest && !tagged
+	}
+}
+
+// platformImage is a single platform image manifest of an export
+type platformImage struct {
+	v1.Descriptor
+	Manifest []byte
+}
+
+// selectImages returns the image of desc or the images of an index matching platform
+func selectImages(repo name.Repository, desc *remote.Descriptor, platform common.PlatformOption, options ...remote.Option) ([]platformImage, error) {
+	if !desc.MediaType.IsIndex() {
+		if !platform.IsDefault() && !platform.All {
+			image, err := desc.Image()
+			if err != nil {
+				return nil, err
+			}
+			config, err := image.ConfigFile()
+			if err != nil {
+				return nil, err
+			}
+			if !platform.Matches(config.Platform()) {
+				return nil, fmt.Errorf("%s does not match platform %s", repo.Digest(desc.Digest.String()).String(), platform.String())
+			}
+		}
+		return []platformImage{{Descriptor: desc.Descriptor, Manifest: desc.Manifest}}, nil
+	}
+	index, err := v1.ParseIndexManifest(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return nil, err
+	}
+	images := []platformImage{}
+	for _, child := range index.Manifests {
+		if !child.MediaType.IsImage() || !platform.Matches(child.Platform) {
+			continue
+		}
+		childDesc, err := remote.Get(repo.Digest(child.Digest.String()), options...)
+		if err != nil {
+			return nil, err
+		}
+		child.Annotations = nil
+		images = append(images, platformImage{Descriptor: child, Manifest: childDesc.Manifest})
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no image of %s matches platform %s", repo.Digest(desc.Digest.String()).String(), platform.String())
+	}
+	return images, nil
+}
+
+func platformOf(desc v1.Descriptor) v1.Platform {
+	if desc.Platform == nil {
+		return v1.Platform{}
+	}
+	return *desc.Platform
+}
+
+// imageWriter writes the manifests and blobs of an export to the layout
+type imageWriter struct {
+	exporter   *Exporter
+	fetcher    *blobFetcher
+	repo       name.Repository
+	jobs       int
+	options    []remote.Option
+	downloaded int64
+	lock       sync.Mutex
+}
+
+// writeIndex writes an index with all its child manifests, nested indexes included
+func (w *imageWriter) writeIndex(digest v1.Hash, manifest []byte) ([]common.PlatformImage, error) {
+	index, err := v1.ParseIndexManifest(bytes.NewReader(manifest))
+	if err != nil {
+		return nil, err
+	}
+	images := []common.PlatformImage{}
+	for _, child := range index.Manifests {
+		childDesc, err := remote.Get(w.repo.Digest(child.Digest.String()), w.options...)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case child.MediaType.IsIndex():
+			nested, err := w.writeIndex(child.Digest, childDesc.Manifest)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, nested...)
+		case child.MediaType.IsImage():
+			if err := w.writeImage(child.Digest, childDesc.Manifest); err != nil {
+				return nil, err
+			}
+			images = append(images, common.PlatformImage{Platform: platformOf(child), Digest: child.Digest.String()})
+		default:
+			//artifacts of the index are kept as is
+			if err := w.writeManifest(child.Digest, childDesc.Manifest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return images, w.writeManifest(digest, manifest)
+}
+
+// writeImage downloads the config and the layers of an image then writes its manifest,
+// the manifest is written last so that a layout manifest always has all its blobs
+func (w *imageWriter) writeImage(digest v1.Hash, manifest []byte) error {
+	parsed, err := v1.ParseManifest(bytes.NewReader(manifest))
+	if err != nil {
+		return err
+	}
+	if parsed.Config.Digest.Hex == "" {
+		return fmt.Errorf("%s is not an OCI or docker schema2 image manifest", w.repo.Digest(digest.String()).String())
+	}
+	blobs := append([]v1.Descriptor{parsed.Config}, parsed.Layers...)
+	errs := make([]error, len(blobs))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for range min(w.jobs, len(blobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = w.writeBlob(blobs[i])
+			}
+		}()
+	}
+	for i := range blobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("failed to export blob %s: %w", blobs[i].Digest, err)
+		}
+	}
+	return w.writeManifest(digest, manifest)
+}
+
+func (w *imageWriter) writeBlob(blob v1.Descriptor) error {
+	if !blob.MediaType.IsDistributable() {
+		//foreign layers are pulled from their urls by the runtime
+		return nil
+	}
+	lock, _ := w.exporter.blobLocks.LoadOrStore(blob.Digest, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	downloaded, err := w.fetcher.fetch(w.exporter.layout, blob)
+	w.lock.Lock()
+	w.downloaded += downloaded
+	w.lock.Unlock()
+	return err
+}
+
+func (w *imageWriter) writeManifest(digest v1.Hash, manifest []byte) error {
+	return w.exporter.layout.WriteBlob(digest, io.NopCloser(bytes.NewReader(manifest)))
+}
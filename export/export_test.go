@@ -0,0 +1,303 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries/defaultregistry"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/validate"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingRegistry is an in-memory registry recording the blob downloads with their ranges
+type recordingRegistry struct {
+	lock      sync.Mutex
+	downloads []string
+}
+
+func (r *recordingRegistry) start(t *testing.T) (interfaces.IRegistry, *httptest.Server) {
+	handler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/blobs/sha256:") && req.Method == http.MethodGet {
+			r.lock.Lock()
+			r.downloads = append(r.downloads, req.Header.Get("Range"))
+			r.lock.Unlock()
+		}
+		handler.ServeHTTP(w, req)
+	}))
+	reg, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	iRegistry, err := defaultregistry.NewRegistry(nil, &reg, common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	return iRegistry, server
+}
+
+func (r *recordingRegistry) reset() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	downloads := r.downloads
+	r.downloads = nil
+	return downloads
+}
+
+func randomPlatformImage(t *testing.T, architecture string) v1.Image {
+	image, err := random.Image(64, 2)
+	assert.Nil(t, err)
+	config, err := image.ConfigFile()
+	assert.Nil(t, err)
+	config.OS, config.Architecture = "linux", architecture
+	image, err = mutate.ConfigFile(image, config)
+	assert.Nil(t, err)
+	return image
+}
+
+// platformIndex is a two platforms index
+func platformIndex(t *testing.T) v1.ImageIndex {
+	amd64, arm64 := randomPlatformImage(t, "amd64"), randomPlatformImage(t, "arm64")
+	return mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: amd64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: arm64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}}},
+	)
+}
+
+func TestExportLayout(t *testing.T) {
+	recorder := &recordingRegistry{}
+	reg, server := recorder.start(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("team/app", reg.GetRegistry())
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	image, err := random.Image(64, 3)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("1.0"), image))
+	assert.Nil(t, remote.Write(repo.Tag("stable"), image))
+	imageDigest, err := image.Digest()
+	assert.Nil(t, err)
+	index := platformIndex(t)
+	assert.Nil(t, remote.WriteIndex(repo.Tag("multi"), index))
+	indexDigest, err := index.Digest()
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	results, err := ExportLayout(ctx, dir, []Image{
+		{Registry: reg, Repository: "team/app", Reference: "1.0"},
+		{Registry: reg, Repository: "team/app", Reference: "multi"},
+	}, ExportOption{})
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, imageDigest.String(), results[0].Digest)
+	assert.Equal(t, indexDigest.String(), results[1].Digest)
+	assert.Len(t, results[1].Images, 2)
+	//config + 3 layers, then 2 configs + 4 layers
+	assert.Len(t, recorder.reset(), 10)
+
+	path, err := layout.FromPath(dir)
+	assert.Nil(t, err)
+	layoutIndex, err := path.ImageIndex()
+	assert.Nil(t, err)
+	manifest, err := layoutIndex.IndexManifest()
+	assert.Nil(t, err)
+	assert.Len(t, manifest.Manifests, 2)
+	assert.Equal(t, "team/app:1.0", manifest.Manifests[0].Annotations[common.RefNameAnnotation])
+	assert.Equal(t, repo.Tag("1.0").Name(), manifest.Manifests[0].Annotations[common.ImageNameAnnotation])
+	assert.Equal(t, "team/app:multi", manifest.Manifests[1].Annotations[common.RefNameAnnotation])
+	layoutImage, err := layoutIndex.Image(imageDigest)
+	assert.Nil(t, err)
+	assert.Nil(t, validate.Image(layoutImage))
+	child, err := layoutIndex.ImageIndex(indexDigest)
+	assert.Nil(t, err)
+	assert.Nil(t, validate.Index(child))
+
+	//the blobs are shared with the previous exports
+	results, err = ExportLayout(ctx, dir, []Image{{Registry: reg, Repository: "team/app", Reference: "stable"}}, ExportOption{})
+	assert.Nil(t, err)
+	assert.Zero(t, results[0].Downloaded)
+	assert.Empty(t, recorder.reset())
+	//exporting a tag again replaces it
+	_, err = ExportLayout(ctx, dir, []Image{{Registry: reg, Repository: "team/app", Reference: "1.0"}}, ExportOption{})
+	assert.Nil(t, err)
+	layoutIndex, err = path.ImageIndex()
+	assert.Nil(t, err)
+	indexManifest, err := layoutIndex.IndexManifest()
+	assert.Nil(t, err)
+	assert.Len(t, indexManifest.Manifests, 3)
+
+	//a selected platform is exported as an image
+	results, err = ExportLayout(ctx, t.TempDir(), []Image{{Registry: reg, Repository: "team/app", Reference: "multi"}}, ExportOption{Platform: &common.PlatformOption{OS: "linux", Architecture: "arm64"}})
+	assert.Nil(t, err)
+	assert.Len(t, results[0].Images, 1)
+	assert.Equal(t, "arm64", results[0].Images[0].Platform.Architecture)
+	assert.Equal(t, "arm64", results[0].Manifests[0].Platform.Architecture)
+
+	_, err = ExportLayout(ctx, t.TempDir(), []Image{{Registry: reg, Repository: "team/app", Reference: "multi"}}, ExportOption{Platform: &common.PlatformOption{OS: "windows", Architecture: "amd64"}})
+	assert.Error(t, err)
+}
+
+func TestExportResume(t *testing.T) {
+	recorder := &recordingRegistry{}
+	reg, server := recorder.start(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("app", reg.GetRegistry())
+	assert.Nil(t, err)
+	image, err := random.Image(1024, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("1.0"), image))
+	layers, err := image.Layers()
+	assert.Nil(t, err)
+	layerDigest, err := layers[0].Digest()
+	assert.Nil(t, err)
+	layerSize, err := layers[0].Size()
+	assert.Nil(t, err)
+	compressed, err := layers[0].Compressed()
+	assert.Nil(t, err)
+	content := make([]byte, 100)
+	_, err = io.ReadFull(compressed, content)
+	assert.Nil(t, err)
+	compressed.Close()
+
+	dir := t.TempDir()
+	exporter, err := NewExporter(dir)
+	assert.Nil(t, err)
+	blobs := filepath.Join(dir, "blobs", "sha256")
+	assert.Nil(t, os.MkdirAll(blobs, os.ModePerm))
+	//an interrupted download
+	assert.Nil(t, os.WriteFile(filepath.Join(blobs, layerDigest.Hex+partialSuffix), content, 0644))
+
+	result, err := exporter.Export(context.Background(), Image{Registry: reg, Repository: "app", Reference: "1.0"}, ExportOption{})
+	assert.Nil(t, err)
+	assert.Contains(t, recorder.reset(), fmt.Sprintf("bytes=100-%d", layerSize-1))
+	imageDigest, err := image.Digest()
+	assert.Nil(t, err)
+	layoutImage, err := exporter.Layout().Image(imageDigest)
+	assert.Nil(t, err)
+	assert.Nil(t, validate.Image(layoutImage))
+	_, err = os.Stat(filepath.Join(blobs, layerDigest.Hex+partialSuffix))
+	assert.True(t, os.IsNotExist(err))
+	config, err := image.RawConfigFile()
+	assert.Nil(t, err)
+	assert.Equal(t, layerSize-100+int64(len(config)), result.Downloaded)
+
+	//a corrupted partial download is downloaded again
+	dir = t.TempDir()
+	exporter, err = NewExporter(dir)
+	assert.Nil(t, err)
+	blobs = filepath.Join(dir, "blobs", "sha256")
+	assert.Nil(t, os.MkdirAll(blobs, os.ModePerm))
+	assert.Nil(t, os.WriteFile(filepath.Join(blobs, layerDigest.Hex+partialSuffix), []byte("corrupted"), 0644))
+	_, err = exporter.Export(context.Background(), Image{Registry: reg, Repository: "app", Reference: "1.0"}, ExportOption{})
+	assert.Nil(t, err)
+	assert.Contains(t, recorder.reset(), "")
+	layoutImage, err = exporter.Layout().Image(imageDigest)
+	assert.Nil(t, err)
+	assert.Nil(t, validate.Image(layoutImage))
+
+	//an exported blob of the right size but another digest is downloaded again
+	assert.Nil(t, os.WriteFile(filepath.Join(blobs, layerDigest.Hex), make([]byte, layerSize), 0644))
+	result, err = exporter.Export(context.Background(), Image{Registry: reg, Repository: "app", Reference: "1.0"}, ExportOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, layerSize, result.Downloaded)
+	layoutImage, err = exporter.Layout().Image(imageDigest)
+	assert.Nil(t, err)
+	assert.Nil(t, validate.Image(layoutImage))
+}
+
+func TestExportTransport(t *testing.T) {
+	server := httptest.NewTLSServer(registry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	image, err := random.Image(256, 1)
+	assert.Nil(t, err)
+	ref, err := name.ParseReference(host + "/app:1.0")
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(ref, image, remote.WithTransport(server.Client().Transport)))
+	imageDigest, err := image.Digest()
+	assert.Nil(t, err)
+	registryName, err := name.NewRegistry(host)
+	assert.Nil(t, err)
+
+	//the certificate of the registry is not verified with SkipTLSVerify
+	reg, err := defaultregistry.NewRegistry(nil, &registryName, common.MakeRegistryOptions(false, false, true, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	exporter, err := NewExporter(t.TempDir())
+	assert.Nil(t, err)
+	_, err = exporter.Export(context.Background(), Image{Registry: reg, Repository: "app", Reference: "1.0"}, ExportOption{})
+	assert.Nil(t, err)
+	layoutImage, err := exporter.Layout().Image(imageDigest)
+	assert.Nil(t, err)
+	assert.Nil(t, validate.Image(layoutImage))
+
+	//the blobs are downloaded with the transport of the caller options
+	reg, err = defaultregistry.NewRegistry(nil, &registryName, common.MakeRegistryOptions(false, false, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	exporter, err = NewExporter(t.TempDir())
+	assert.Nil(t, err)
+	_, err = exporter.Export(context.Background(), Image{Registry: reg, Repository: "app", Reference: "1.0"}, ExportOption{})
+	assert.Error(t, err)
+	_, err = exporter.Export(context.Background(), Image{Registry: reg, Repository: "app", Reference: "1.0"}, ExportOption{Options: []remote.Option{remote.WithTransport(server.Client().Transport)}})
+	assert.Nil(t, err)
+	layoutImage, err = exporter.Layout().Image(imageDigest)
+	assert.Nil(t, err)
+	assert.Nil(t, validate.Image(layoutImage))
+}
+
+func TestExportDockerArchive(t *testing.T) {
+	reg, server := (&recordingRegistry{}).start(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("team/app", reg.GetRegistry())
+	assert.Nil(t, err)
+	ctx := context.Background()
+	image, err := random.Image(64, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("1.0"), image))
+	index := platformIndex(t)
+	assert.Nil(t, remote.WriteIndex(repo.Tag("multi"), index))
+
+	path := filepath.Join(t.TempDir(), "images.tar")
+	results, err := ExportDockerArchive(ctx, path, "", []Image{
+		{Registry: reg, Repository: "team/app", Reference: "1.0"},
+		{Registry: reg, Repository: "team/app", Reference: "multi"},
+	}, ExportOption{})
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	//the default platform of the index
+	assert.Len(t, results[1].Images, 1)
+	assert.Equal(t, "amd64", results[1].Images[0].Platform.Architecture)
+
+	manifest, err := tarball.LoadManifest(func() (io.ReadCloser, error) { return os.Open(path) })
+	assert.Nil(t, err)
+	assert.Len(t, manifest, 2)
+	for _, tag := range []string{"1.0", "multi"} {
+		tag := repo.Tag(tag)
+		archived, err := tarball.ImageFromPath(path, &tag)
+		assert.Nil(t, err)
+		assert.Nil(t, validate.Image(archived))
+	}
+	tag := repo.Tag("1.0")
+	archived, err := tarball.ImageFromPath(path, &tag)
+	assert.Nil(t, err)
+	archivedConfig, err := archived.ConfigName()
+	assert.Nil(t, err)
+	config, err := image.ConfigName()
+	assert.Nil(t, err)
+	assert.Equal(t, config, archivedConfig)
+}
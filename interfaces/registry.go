@@ -12,7 +12,16 @@ import (
 type IRegistry interface {
 	Catalog(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption, authenticator authn.Authenticator) (repositories []string, nextPage *common.PaginationOption, err error)
 	List(repoName string, pagination common.PaginationOption, options ...remote.Option) (tags []string, nextPagination *common.PaginationOption, err error)
+	ListWithContext(ctx context.Context, repoName string, pagination common.PaginationOption, options ...remote.Option) (tags []string, nextPagination *common.PaginationOption, err error)
 	GetLatestTags(repoName string, depth int, options ...remote.Option) (tags []string, err error)
+	GetLatestTagsWithContext(ctx context.Context, repoName string, depth int, options ...remote.Option) (tags []string, err error)
+	GetLatestImages(ctx context.Context, repoName string, depth int, opts common.LatestTagsOption, options ...remote.Option) (images []common.TaggedImage, err error)
+	Inspect(ctx context.Context, repoName string, reference string, platform common.PlatformOption, options ...remote.Option) (*common.ImageInspection, error)
+	Resolve(ctx context.Context, repoName string, reference string, options ...remote.Option) (digest string, err error)
+	Retag(ctx context.Context, repoName string, reference string, newTag string, options ...remote.Option) (digest string, err error)
+	DeleteTag(ctx context.Context, repoName string, tag string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error)
+	DeleteManifest(ctx context.Context, repoName string, digest string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error)
+	Referrers(ctx context.Context, repoName string, digest string, options ...remote.Option) ([]common.Referrer, error)
 	GetAuth() *authn.AuthConfig
 	GetRegistry() *name.Registry
 	GetMaxPageSize() int
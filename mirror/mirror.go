@@ -0,0 +1,164 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// CopyOption controls an image copy between registries
+type CopyOption struct {
+	// Referrers also copies the artifacts attached to the image (signatures, SBOMs, attestations),
+	// and to the child manifests of an index
+	Referrers bool
+	// Progress is called with the bytes uploaded by every manifest write
+	Progress func(Progress)
+	// Jobs is the number of concurrent blob uploads (go-containerregistry default if 0)
+	Jobs int
+	// SourceOptions and TargetOptions are appended to the options built from the registries credentials
+	SourceOptions []remote.Option
+	TargetOptions []remote.Option
+}
+
+// Progress reports the upload of a manifest and its blobs
+type Progress struct {
+	Reference string // the target reference being written
+	Complete  int64
+	Total     int64
+	Err       error
+}
+
+// CopyResult describes a copied image
+type CopyResult struct {
+	Source    string
+	Target    string
+	Digest    string
+	Referrers []string // digests of the copied referrers
+}
+
+// Copy copies a tag or a digest, with all the platforms of an index, from srcRepo in src to dstRepo in dst.
+// The target is tagged with dstTag, or with the source tag when dstTag is empty (digests are copied untagged).
+// Blobs already in the target repository are skipped and blobs of another repository of the same registry are mounted
+func Copy(ctx context.Context, src interfaces.IRegistry, srcRepo, reference string, dst interfaces.IRegistry, dstRepo, dstTag string, opts CopyOption) (*CopyResult, error) {
+	srcRef, err := common.MakeReference(srcRepo, reference, src.GetRegistry())
+	if err != nil {
+		return nil, err
+	}
+	srcOptions := append(registries.RemoteOptions(ctx, src), opts.SourceOptions...)
+	desc, err := remote.Get(srcRef, srcOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if dstTag == "" {
+		if tag, ok := srcRef.(name.Tag); ok {
+			dstTag = tag.TagStr()
+		}
+	}
+	target := dstTag
+	if target == "" {
+		target = desc.Digest.String()
+	}
+	dstRef, err := common.MakeReference(dstRepo, target, dst.GetRegistry())
+	if err != nil {
+		return nil, err
+	}
+	dstOptions := append(registries.RemoteOptions(ctx, dst), opts.TargetOptions...)
+	if opts.Jobs > 0 {
+		dstOptions = append(dstOptions, remote.WithJobs(opts.Jobs))
+	}
+	if err := write(dstRef, desc, opts.Progress, dstOptions...); err != nil {
+		return nil, err
+	}
+	result := &CopyResult{Source: srcRef.String(), Target: dstRef.String(), Digest: desc.Digest.String()}
+	if !opts.Referrers {
+		return result, nil
+	}
+	subjects, err := referrerSubjects(desc)
+	if err != nil {
+		return nil, err
+	}
+	copied := map[string]bool{}
+	for _, subject := range subjects {
+		referrers, err := src.Referrers(ctx, srcRepo, subject, srcOptions...)
+		if err != nil {
+			return nil, err
+		}
+		for _, referrer := range referrers {
+			if copied[referrer.Digest] {
+				continue
+			}
+			if err := copyReferrer(srcRepo, dstRepo, src, dst, referrer, srcOptions, dstOptions, opts.Progress); err != nil {
+				return nil, fmt.Errorf("failed to copy referrer %s: %w", referrer.Digest, err)
+			}
+			copied[referrer.Digest] = true
+			result.Referrers = append(result.Referrers, referrer.Digest)
+		}
+	}
+	return result, nil
+}
+
+// referrerSubjects returns the digests whose referrers are copied: the copied manifest and the child manifests of an index
+func referrerSubjects(desc *remote.Descriptor) ([]string, error) {
+	subjects := []string{desc.Digest.String()}
+	if !desc.MediaType.IsIndex() {
+		return subjects, nil
+	}
+	index, err := desc.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range manifest.Manifests {
+		subjects = append(subjects, child.Digest.String())
+	}
+	return subjects, nil
+}
+
+// copyReferrer copies an artifact by digest (the target registry indexes it by its subject),
+// cosign tag based artifacts keep their tag
+func copyReferrer(srcRepo, dstRepo string, src, dst interfaces.IRegistry, referrer common.Referrer, srcOptions, dstOptions []remote.Option, progress func(Progress)) error {
+	srcRef, err := common.MakeReference(srcRepo, referrer.Digest, src.GetRegistry())
+	if err != nil {
+		return err
+	}
+	desc, err := remote.Get(srcRef, srcOptions...)
+	if err != nil {
+		return err
+	}
+	target := referrer.Digest
+	if referrer.Tag != "" {
+		target = referrer.Tag
+	}
+	dstRef, err := common.MakeReference(dstRepo, target, dst.GetRegistry())
+	if err != nil {
+		return err
+	}
+	return write(dstRef, desc, progress, dstOptions...)
+}
+
+// write pushes a manifest with its child manifests and blobs to ref
+func write(ref name.Reference, desc *remote.Descriptor, progress func(Progress), options ...remote.Option) error {
+	if progress != nil {
+		updates := make(chan v1.Update, 100)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for update := range updates {
+				progress(Progress{Reference: ref.String(), Complete: update.Complete, Total: update.Total, Err: update.Error})
+			}
+		}()
+		//the updates channel is closed when the push returns
+		defer func() { <-done }()
+		options = append(options, remote.WithProgress(updates))
+	}
+	return remote.Push(ref, desc, options...)
+}
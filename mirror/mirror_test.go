@@ -0,0 +1,211 @@
+package mirror
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries/defaultregistry"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingRegistry is an in-memory registry recording the blob upload requests
+type recordingRegistry struct {
+	lock    sync.Mutex
+	uploads []string
+}
+
+func (r *recordingRegistry) start(t *testing.T) (interfaces.IRegistry, *httptest.Server) {
+	handler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/blobs/uploads/") && req.Method == http.MethodPost {
+			r.lock.Lock()
+			r.uploads = append(r.uploads, req.URL.String())
+			r.lock.Unlock()
+		}
+		handler.ServeHTTP(w, req)
+	}))
+	reg, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	iRegistry, err := defaultregistry.NewRegistry(nil, &reg, common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	return iRegistry, server
+}
+
+func (r *recordingRegistry) reset() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	uploads := r.uploads
+	r.uploads = nil
+	return uploads
+}
+
+func TestCopy(t *testing.T) {
+	srcRecorder, dstRecorder := &recordingRegistry{}, &recordingRegistry{}
+	src, srcServer := srcRecorder.start(t)
+	defer srcServer.Close()
+	dst, dstServer := dstRecorder.start(t)
+	defer dstServer.Close()
+	srcRepo, err := common.MakeRepoWithRegistry("library/base", src.GetRegistry())
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	index, err := random.Index(64, 2, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.WriteIndex(srcRepo.Tag("1.0"), index))
+	indexDigest, err := index.Digest()
+	assert.Nil(t, err)
+	//referrers - an OCI SBOM and a cosign signature tag
+	subject, err := remote.Head(srcRepo.Digest(indexDigest.String()))
+	assert.Nil(t, err)
+	sbom := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), common.SPDXMediaType)
+	sbom = mutate.Subject(sbom, *subject).(v1.Image)
+	sbomDigest, err := sbom.Digest()
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(srcRepo.Digest(sbomDigest.String()), sbom))
+	signature, err := random.Image(16, 1)
+	assert.Nil(t, err)
+	signatureTag := common.CosignTag(indexDigest.String(), common.CosignSignatureTagSuffix)
+	assert.Nil(t, remote.Write(srcRepo.Tag(signatureTag), signature))
+	srcRecorder.reset()
+
+	var lock sync.Mutex
+	progress := map[string]Progress{}
+	result, err := Copy(ctx, src, "library/base", "1.0", dst, "mirror/base", "", CopyOption{
+		Referrers: true,
+		Progress: func(p Progress) {
+			lock.Lock()
+			defer lock.Unlock()
+			progress[p.Reference] = p
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, indexDigest.String(), result.Digest)
+	assert.Equal(t, dst.GetRegistry().Name()+"/mirror/base:1.0", result.Target)
+	assert.ElementsMatch(t, []string{sbomDigest.String(), mustDigest(t, signature)}, result.Referrers)
+	assert.NotZero(t, progress[result.Target].Total)
+	assert.NotZero(t, progress[result.Target].Complete)
+
+	//the index and its platforms are in the target
+	digest, err := dst.Resolve(ctx, "mirror/base", "1.0")
+	assert.Nil(t, err)
+	assert.Equal(t, indexDigest.String(), digest)
+	manifest, err := index.IndexManifest()
+	assert.Nil(t, err)
+	for _, child := range manifest.Manifests {
+		_, err := dst.Inspect(ctx, "mirror/base", child.Digest.String(), common.PlatformOption{})
+		assert.Nil(t, err)
+	}
+	referrers, err := dst.Referrers(ctx, "mirror/base", indexDigest.String())
+	assert.Nil(t, err)
+	assert.Len(t, referrers, 2)
+	assert.Equal(t, signatureTag, referrers[1].Tag)
+	//4 layers and 2 configs of the index, a layer and a config of the signature, the SBOM config
+	assert.Len(t, dstRecorder.reset(), 9)
+
+	//existing blobs are skipped
+	_, err = Copy(ctx, src, "library/base", "1.0", dst, "mirror/base", "1.0-copy", CopyOption{Referrers: true})
+	assert.Nil(t, err)
+	assert.Empty(t, dstRecorder.reset())
+
+	//digests are copied untagged, the in-memory registry shares the blobs of all its repositories
+	result, err = Copy(ctx, dst, "mirror/base", indexDigest.String(), dst, "mirror/other", "", CopyOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, dst.GetRegistry().Name()+"/mirror/other@"+indexDigest.String(), result.Target)
+	assert.Empty(t, dstRecorder.reset())
+
+	_, err = Copy(ctx, src, "library/base", "missing", dst, "mirror/base", "", CopyOption{})
+	assert.Error(t, err)
+}
+
+func mustDigest(t *testing.T, image v1.Image) string {
+	digest, err := image.Digest()
+	assert.Nil(t, err)
+	return digest.String()
+}
+
+func TestCopyPrivateSourceReferrers(t *testing.T) {
+	handler := registry.New()
+	srcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if username, password, ok := req.BasicAuth(); !ok || username != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	}))
+	defer srcServer.Close()
+	srcRegistry, err := name.NewRegistry(strings.TrimPrefix(srcServer.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	src, err := defaultregistry.NewRegistry(&authn.AuthConfig{Username: "user", Password: "secret"}, &srcRegistry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	dst, dstServer := (&recordingRegistry{}).start(t)
+	defer dstServer.Close()
+	srcRepo, err := common.MakeRepoWithRegistry("private/app", src.GetRegistry())
+	assert.Nil(t, err)
+	withAuth := remote.WithAuth(&authn.Basic{Username: "user", Password: "secret"})
+
+	index, err := random.Index(64, 1, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.WriteIndex(srcRepo.Tag("1.0"), index, withAuth))
+	//an SBOM of a platform image of the index
+	manifest, err := index.IndexManifest()
+	assert.Nil(t, err)
+	child := manifest.Manifests[0]
+	sbom := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), common.SPDXMediaType)
+	sbom = mutate.Subject(sbom, child).(v1.Image)
+	assert.Nil(t, remote.Write(srcRepo.Digest(mustDigest(t, sbom)), sbom, withAuth))
+
+	result, err := Copy(context.Background(), src, "private/app", "1.0", dst, "mirror/app", "", CopyOption{Referrers: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{mustDigest(t, sbom)}, result.Referrers)
+	referrers, err := dst.Referrers(context.Background(), "mirror/app", child.Digest.String())
+	assert.Nil(t, err)
+	assert.Len(t, referrers, 1)
+}
+
+func TestCopySkipTLSVerify(t *testing.T) {
+	newTLSRegistry := func(skipTLSVerify bool) (interfaces.IRegistry, *httptest.Server) {
+		server := httptest.NewTLSServer(registry.New())
+		reg, err := name.NewRegistry(strings.TrimPrefix(server.URL, "https://"))
+		assert.Nil(t, err)
+		iRegistry, err := defaultregistry.NewRegistry(nil, &reg, common.MakeRegistryOptions(false, false, skipTLSVerify, "", "", "", common.Generic))
+		assert.Nil(t, err)
+		return iRegistry, server
+	}
+	src, srcServer := newTLSRegistry(true)
+	defer srcServer.Close()
+	dst, dstServer := newTLSRegistry(true)
+	defer dstServer.Close()
+	ctx := context.Background()
+
+	image, err := random.Image(256, 1)
+	assert.Nil(t, err)
+	ref, err := name.ParseReference(src.GetRegistry().Name() + "/library/base:1.0")
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(ref, image, remote.WithTransport(srcServer.Client().Transport)))
+
+	//the self-signed certificates are not verified with SkipTLSVerify
+	result, err := Copy(ctx, src, "library/base", "1.0", dst, "mirror/base", "", CopyOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, mustDigest(t, image), result.Digest)
+
+	verified, verifiedServer := newTLSRegistry(false)
+	defer verifiedServer.Close()
+	_, err = Copy(ctx, src, "library/base", "1.0", verified, "mirror/base", "", CopyOption{})
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,8 +18,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
-	"github.com/google/go-containerregistry/pkg/v1/types"
-	version "github.com/hashicorp/go-version"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"k8s.io/utils/strings/slices"
 )
 
@@ -26,6 +26,11 @@ type CatalogV2Response struct {
 	Repositories []string `json:"repositories"`
 }
 
+type TagsListResponse struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
 // this is just a wrapper around the go-container & remote catalog
 type DefaultRegistry struct {
 	Registry    *name.Registry
@@ -64,6 +69,11 @@ func (reg *DefaultRegistry) GetRegistry() *name.Registry {
 	return reg.Registry
 }
 
+// GetRegistryOptions returns the options the registry was created with
+func (reg *DefaultRegistry) GetRegistryOptions() *common.RegistryOptions {
+	return reg.Cfg
+}
+
 func (reg *DefaultRegistry) GetURL(urlSuffix string) *url.URL {
 
 	return &url.URL{
@@ -74,13 +84,131 @@ func (reg *DefaultRegistry) GetURL(urlSuffix string) *url.URL {
 }
 
 func (reg *DefaultRegistry) List(repoName string, pagination common.PaginationOption, options ...remote.Option) ([]string, *common.PaginationOption, error) {
+	return reg.This.ListWithContext(context.Background(), repoName, pagination, options...)
+}
+
+func (reg *DefaultRegistry) ListWithContext(ctx context.Context, repoName string, pagination common.PaginationOption, options ...remote.Option) ([]string, *common.PaginationOption, error) {
 	repoData, err := common.MakeRepoWithRegistry(repoName, reg.Registry)
 	if err != nil {
 		return nil, nil, err
 	}
-	tags, err := remote.List(*repoData, options...)
-	//TODO handle pagination
-	return tags, nil, err
+	if pagination.Size == 0 && pagination.Cursor == "" {
+		//no pagination requested - list all the tags at once
+		tags, err := remote.List(*repoData, withContext(ctx, options)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return reg.Cfg.TagFilter().Apply(tags), nil, nil
+	}
+	var tags []string
+	var nextPage *common.PaginationOption
+	if len(options) > 0 {
+		tags, nextPage, err = reg.listTagsPageWithOptions(ctx, *repoData, pagination, options)
+	} else {
+		tags, nextPage, err = reg.listTagsPage(ctx, *repoData, pagination)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	//the next page is computed from the unfiltered page
+	return reg.Cfg.TagFilter().Apply(tags), nextPage, nil
+}
+
+// listTagsPage requests a single tags page using the n/last params and gets the next page from the response Link header
+// paged requests without options are authenticated with the registry credentials (or anonymously if there are none)
+func (reg *DefaultRegistry) listTagsPage(ctx context.Context, repo name.Repository, pagination common.PaginationOption) ([]string, *common.PaginationOption, error) {
+	client, err := reg.repositoryClient(ctx, repo, transport.PullScope)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tagsPageURL(repo, pagination), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, nil, err
+	}
+	parsed := TagsListResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, nil, err
+	}
+	nextPagination, err := common.GetNextV2Pagination(resp)
+	return parsed.Tags, nextPagination, err
+}
+
+// listTagsPageWithOptions requests a single tags page authenticated and sent by the options of the caller.
+// The options cannot be inspected, so the page is listed by a remote lister: its first page authenticates the listing
+// and the lister then follows the next link set to the requested page
+func (reg *DefaultRegistry) listTagsPageWithOptions(ctx context.Context, repo name.Repository, pagination common.PaginationOption, options []remote.Option) ([]string, *common.PaginationOption, error) {
+	if pagination.Size <= 0 {
+		pagination.Size = reg.GetMaxPageSize()
+	}
+	firstPageSize := pagination.Size
+	if pagination.Cursor != "" {
+		firstPageSize = 1
+	}
+	puller, err := remote.NewPuller(append(withContext(ctx, options), remote.WithPageSize(firstPageSize))...)
+	if err != nil {
+		return nil, nil, err
+	}
+	lister, err := puller.Lister(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := lister.Next(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pagination.Cursor != "" {
+		page.Next = tagsPageURL(repo, pagination)
+		if page, err = lister.Next(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	if page.Next == "" {
+		return page.Tags, nil, nil
+	}
+	nextPage, err := common.ParseNextV2Pagination(page.Next)
+	return page.Tags, nextPage, err
+}
+
+// tagsPageURL returns the URL of a tags page, the n and last params are set by the page size and cursor
+func tagsPageURL(repo name.Repository, pagination common.PaginationOption) string {
+	uri := &url.URL{
+		Scheme: repo.Scheme(),
+		Host:   repo.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/tags/list", repo.RepositoryStr()),
+	}
+	q := uri.Query()
+	if pagination.Size > 0 {
+		q.Add("n", strconv.Itoa(pagination.Size))
+	}
+	if pagination.Cursor != "" {
+		q.Add("last", pagination.Cursor)
+	}
+	uri.RawQuery = q.Encode()
+	return uri.String()
+}
+
+// repositoryClient returns an http client authorized for the given scope of repo
+func (reg *DefaultRegistry) repositoryClient(ctx context.Context, repo name.Repository, scope string) (*http.Client, error) {
+	tr, err := transport.NewWithContext(ctx, repo.Registry, reg.authenticator(), reg.Cfg.Transport(), []string{repo.Scope(scope)})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: tr}, nil
+}
+
+func (reg *DefaultRegistry) authenticator() authn.Authenticator {
+	if err := common.ValidateAuth(reg.GetAuth()); err != nil {
+		return authn.Anonymous
+	}
+	return authn.FromConfig(*reg.GetAuth())
 }
 
 // this is the default catalog implementation uses remote(for now)
@@ -91,14 +219,14 @@ func (reg *DefaultRegistry) Catalog(ctx context.Context, pagination common.Pagin
 		}
 		return reg.CatalogPage(ctx, pagination, options, authenticator)
 	}
-	repos, err := remote.CatalogPage(*reg.GetRegistry(), pagination.Cursor, pagination.Size, remote.WithAuth(authn.Anonymous))
+	repos, err := remote.CatalogPage(*reg.GetRegistry(), pagination.Cursor, pagination.Size, remote.WithAuth(authn.Anonymous), remote.WithContext(ctx))
 
 	return repos, common.CalcNextV2Pagination(repos, pagination.Size), err
 }
 
 // Build http req and append password / token as bearer token
 // See https://cloud.google.com/container-registry/docs/advanced-authentication#token
-func (reg *DefaultRegistry) gcrCatalogPage(pagination common.PaginationOption, options common.CatalogOption) ([]string, *common.PaginationOption, error) {
+func (reg *DefaultRegistry) gcrCatalogPage(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption) ([]string, *common.PaginationOption, error) {
 	uri := reg.GetURL("_catalog")
 	q := uri.Query()
 
@@ -109,7 +237,7 @@ func (reg *DefaultRegistry) gcrCatalogPage(pagination common.PaginationOption, o
 		q.Add("last", pagination.Cursor)
 	}
 	uri.RawQuery = q.Encode()
-	req, err := http.NewRequest(http.MethodGet, uri.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -140,12 +268,8 @@ func (reg *DefaultRegistry) gcrCatalogPage(pagination common.PaginationOption, o
 	if err := json.NewDecoder(resp.Body).Decode(repos); err != nil {
 		return nil, nil, err
 	}
-	pgn := &common.PaginationOption{Size: pagination.Size}
-	if len(repos.Repositories) > 0 && pagination.Size > 0 {
-		pgn.Cursor = repos.Repositories[len(repos.Repositories)-1]
-	}
-
-	return repos.Repositories, pgn, nil
+	pgn, err := common.NextV2Pagination(resp, repos.Repositories, pagination.Size)
+	return repos.Repositories, pgn, err
 }
 
 func (reg *DefaultRegistry) CatalogPage(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption, authenticator authn.Authenticator) ([]string, *common.PaginationOption, error) {
@@ -154,20 +278,20 @@ func (reg *DefaultRegistry) CatalogPage(ctx context.Context, pagination common.P
 	var pgn *common.PaginationOption
 	switch provider := getRegistryProvider(reg.Registry.RegistryStr()); provider {
 	case "gcr":
-		repos, pgn, err = reg.gcrCatalogPage(pagination, options)
+		repos, pgn, err = reg.gcrCatalogPage(ctx, pagination, options)
 	default:
-		repos, err = remote.CatalogPage(*reg.GetRegistry(), pagination.Cursor, pagination.Size, remote.WithAuth(authenticator))
+		repos, err = remote.CatalogPage(*reg.GetRegistry(), pagination.Cursor, pagination.Size, remote.WithAuth(authenticator), remote.WithContext(ctx))
 		pgn = common.CalcNextV2Pagination(repos, pagination.Size)
 	}
 	return repos, pgn, err
 
 }
 
-func (reg *DefaultRegistry) GetV2Token(client *http.Client, url string) (*common.V2TokenResponse, error) {
+func (reg *DefaultRegistry) GetV2Token(ctx context.Context, client *http.Client, url string) (*common.V2TokenResponse, error) {
 	if reg.GetAuth() == nil {
 		return nil, fmt.Errorf("no authorization found")
 	}
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -201,38 +325,67 @@ func (reg *DefaultRegistry) GetV2Token(client *http.Client, url string) (*common
 // multiple tags on a single image will be sent as a comma separated string
 // e.g ["latest,v3" ,"v2", "v1"]
 func (reg *DefaultRegistry) GetLatestTags(repoName string, depth int, options ...remote.Option) ([]string, error) {
+	return reg.This.GetLatestTagsWithContext(context.Background(), repoName, depth, options...)
+}
+
+// GetLatestTagsWithContext is GetLatestTags bound to ctx, cancelling ctx stops the tags listing and the image fetching
+func (reg *DefaultRegistry) GetLatestTagsWithContext(ctx context.Context, repoName string, depth int, options ...remote.Option) ([]string, error) {
+	images, err := reg.This.GetLatestImages(ctx, repoName, depth, common.LatestTagsOption{}, options...)
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, image := range images {
+		tags = append(tags, strings.Join(image.Tags, ","))
+	}
+	return tags, nil
+}
+
+// GetLatestImages returns the latest images of a given repository in descending order by the image creation time
+// (or by opts.Strategy). Multi-platform images are reported by their index digest, ranked by the images of the selected platform,
+// tags without an image for the selected platform are skipped
+func (reg *DefaultRegistry) GetLatestImages(ctx context.Context, repoName string, depth int, opts common.LatestTagsOption, options ...remote.Option) ([]common.TaggedImage, error) {
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = common.CreatedStrategy()
+	}
+	if strategy.RanksTags() {
+		return reg.latestImagesByTag(ctx, repoName, depth, opts.Platform, opts.Filter, strategy, options...)
+	}
 	type imageInfo struct {
-		created time.Time
-		digest  string
-		tag     string
-		err     error
+		image *common.TaggedImage
+		tag   string
+		err   error
 	}
-	tagsInfos := tagsInfo{}
+	latestImages := taggedImages{}
 	wg := sync.WaitGroup{}
-	ctx, cancelCtx := context.WithCancel(context.Background())
+	ctx, cancelCtx := context.WithCancel(ctx)
 	defer cancelCtx()
-	for tagsPage, nextPage, err := reg.This.List(repoName, common.MakePagination(reg.This.GetMaxPageSize()), options...); ; tagsPage, nextPage, err = reg.This.List(repoName, *nextPage, options...) {
+	for tagsPage, nextPage, err := reg.This.ListWithContext(ctx, repoName, common.MakePagination(reg.This.GetMaxPageSize()), options...); ; tagsPage, nextPage, err = reg.This.ListWithContext(ctx, repoName, *nextPage, options...) {
 		if err != nil {
 			return nil, err
 		}
 		//if depth is one (default) and latest tag found no need to continue
-		if depth == 1 && slices.Contains(tagsPage, "latest") {
-			return []string{"latest"}, nil
+		if depth == 1 && opts.Strategy == nil && opts.Platform.IsDefault() && slices.Contains(tagsPage, "latest") && opts.Filter.Matches("latest") {
+			return []common.TaggedImage{{Tags: []string{"latest"}}}, nil
 		}
 
-		tagsChunks := split2Chunks(30, tagsPage)
+		tagsChunks := split2Chunks(30, acceptedTags(strategy, opts.Filter.Apply(tagsPage)))
 		ch := make(chan imageInfo, len(tagsChunks))
 		for _, tags := range tagsChunks {
 			wg.Add(1)
 			go func(ch chan<- imageInfo, tags []string, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, tag := range tags {
+					if ctx.Err() != nil {
+						return
+					}
 					imageName := fmt.Sprintf("%s/%s:%s", reg.Registry.Name(), repoName, tag)
-					digest, created, err := reg.getImageDigestAndCreationTime(imageName, options...)
+					image, err := reg.describeImage(ctx, imageName, opts.Platform, options...)
 					select {
 					case <-ctx.Done():
 						return
-					case ch <- imageInfo{created: created, digest: digest, tag: tag, err: err}:
+					case ch <- imageInfo{image: image, tag: tag, err: err}:
 					}
 				}
 			}(ch, tags, &wg)
@@ -247,20 +400,28 @@ func (reg *DefaultRegistry) GetLatestTags(repoName string, depth int, options ..
 			if info.err != nil {
 				return nil, info.err
 			}
+			//no image for the selected platform
+			if info.image == nil {
+				continue
+			}
 			//check if the image already collected with different tag
-			if existingImage := tagsInfos.getByDigest(info.digest); existingImage != nil {
-				existingImage.tags = append(existingImage.tags, info.tag)
+			if existingImage := latestImages.getByDigest(info.image.Digest); existingImage != nil {
+				existingImage.Tags = append(existingImage.Tags, info.tag)
 			} else { //new image add it with the tag
-				tagsInfos = append(tagsInfos, &tagInfo{tags: []string{info.tag}, created: info.created, digest: info.digest})
+				info.image.Tags = []string{info.tag}
+				latestImages = append(latestImages, info.image)
 			}
 		}
-		//sort the list by creation time
-		sort.Slice(tagsInfos, func(i, j int) bool {
-			return tagsInfos[i].created.After(tagsInfos[j].created)
+		//sort the list by the strategy (creation time by default)
+		sort.SliceStable(latestImages, func(i, j int) bool {
+			return strategy.NewerImage(latestImages[i], latestImages[j])
 		})
 		//cut off the list tail if we have reached the depth
-		if len(tagsInfos) > depth {
-			tagsInfos = tagsInfos[:depth]
+		if len(latestImages) > depth {
+			latestImages = latestImages[:depth]
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 		//if no next page then we are done
 		if nextPage == nil {
@@ -268,42 +429,101 @@ func (reg *DefaultRegistry) GetLatestTags(repoName string, depth int, options ..
 		}
 	}
 
-	//sort multiple tags on a single image by version if possible otherwise sort by sematic version otherwise sort alphabetically
-	for _, tagInfo := range tagsInfos {
-		if len(tagInfo.tags) == 1 {
-			continue
+	images := []common.TaggedImage{}
+	for _, image := range latestImages {
+		common.SortImageTags(image.Tags)
+		images = append(images, *image)
+	}
+	return images, nil
+}
+
+// latestImagesByTag returns the images of the newest tags ranked by a tag strategy,
+// only the newest tags of each page are kept and their manifests fetched until depth images are found
+func (reg *DefaultRegistry) latestImagesByTag(ctx context.Context, repoName string, depth int, platform common.PlatformOption, filter *common.TagFilter, strategy common.LatestTagStrategy, options ...remote.Option) ([]common.TaggedImage, error) {
+	if depth < 1 {
+		return []common.TaggedImage{}, nil
+	}
+	candidates := []taggedImage{}
+	for tagsPage, nextPage, err := reg.This.ListWithContext(ctx, repoName, common.MakePagination(reg.This.GetMaxPageSize()), options...); ; tagsPage, nextPage, err = reg.This.ListWithContext(ctx, repoName, *nextPage, options...) {
+		if err != nil {
+			return nil, err
 		}
-		sort.Slice(tagInfo.tags, func(i, j int) bool {
-			//latest always comes first
-			if tagInfo.tags[i] == "latest" {
-				return true
-			}
-			if tagInfo.tags[j] == "latest" {
-				return false
-			}
-			//try to parse the version
-			vi, erri := version.NewVersion(tagInfo.tags[i])
-			vj, errj := version.NewVersion(tagInfo.tags[j])
-			if erri == nil && errj == nil {
-				return vj.LessThan(vi)
+		tags := acceptedTags(strategy, filter.Apply(tagsPage))
+		sort.SliceStable(tags, func(i, j int) bool {
+			return strategy.NewerTag(tags[i], tags[j])
+		})
+		for _, tag := range tags {
+			//the page tags are ordered, the rest are older than the kept images
+			if len(imagesOf(candidates)) >= depth && !strategy.NewerTag(tag, candidates[len(candidates)-1].tag) {
+				break
 			}
-			if erri != nil && errj != nil {
-				//no version so sort alphabetically
-				return strings.ToLower(tagInfo.tags[i]) > strings.ToLower(tagInfo.tags[j])
+			imageName := fmt.Sprintf("%s/%s:%s", reg.Registry.Name(), repoName, tag)
+			image, err := reg.describeImage(ctx, imageName, platform, options...)
+			if err != nil {
+				return nil, err
 			}
-			//advance versions over non-versions
-			if erri == nil {
-				return true
+			if image == nil {
+				continue
 			}
-			return false
-		})
+			candidates = append(candidates, taggedImage{tag: tag, image: image})
+			sort.SliceStable(candidates, func(i, j int) bool {
+				return strategy.NewerTag(candidates[i].tag, candidates[j].tag)
+			})
+			candidates = newestCandidates(candidates, depth)
+		}
+		if nextPage == nil {
+			break
+		}
 	}
-	tags := []string{}
-	for _, tagInfo := range tagsInfos {
-		tags = append(tags, strings.Join(tagInfo.tags, ","))
+	//candidates are already ordered by the strategy
+	images := []common.TaggedImage{}
+	for _, image := range imagesOf(candidates) {
+		images = append(images, *image)
 	}
-	return tags, nil
+	return images, nil
+}
+
+// taggedImage is a tag ranked by a tag strategy and the image it points to
+type taggedImage struct {
+	tag   string
+	image *common.TaggedImage
+}
+
+// newestCandidates cuts off the ordered candidates after the first tag of the depth-th image
+func newestCandidates(candidates []taggedImage, depth int) []taggedImage {
+	digests := map[string]struct{}{}
+	for i, candidate := range candidates {
+		digests[candidate.image.Digest] = struct{}{}
+		if len(digests) == depth {
+			return candidates[:i+1]
+		}
+	}
+	return candidates
+}
+
+// imagesOf groups the ordered candidates by image, keeping the order of the newest tag of each image
+func imagesOf(candidates []taggedImage) taggedImages {
+	images := taggedImages{}
+	for _, candidate := range candidates {
+		if existingImage := images.getByDigest(candidate.image.Digest); existingImage != nil {
+			existingImage.Tags = append(existingImage.Tags, candidate.tag)
+			continue
+		}
+		image := *candidate.image
+		image.Tags = []string{candidate.tag}
+		images = append(images, &image)
+	}
+	return images
+}
 
+func acceptedTags(strategy common.LatestTagStrategy, tags []string) []string {
+	accepted := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if strategy.Accepts(tag) {
+			accepted = append(accepted, tag)
+		}
+	}
+	return accepted
 }
 
 func split2Chunks[T any](maxNumOfChunks int, slice []T) [][]T {
@@ -323,64 +543,84 @@ func split2Chunks[T any](maxNumOfChunks int, slice []T) [][]T {
 	return divided
 }
 
-func (*DefaultRegistry) getImageDigestAndCreationTime(imageName string, options ...remote.Option) (string, time.Time, error) {
+// describeImage returns the digest and creation time of an image,
+// for an index it returns the index digest with the images matching platform (nil if none)
+func (*DefaultRegistry) describeImage(ctx context.Context, imageName string, platform common.PlatformOption, options ...remote.Option) (*common.TaggedImage, error) {
 	ref, err := name.ParseReference(imageName)
 	if err != nil {
-		return "", time.Time{}, err
+		return nil, err
 	}
-	desc, err := remote.Get(ref, options...)
+	desc, err := remote.Get(ref, withContext(ctx, options)...)
 	if err != nil {
-		return "", time.Time{}, err
+		return nil, err
 	}
 
-	//first try to covert to image - this works only for schema v2
-	if image, err := desc.Image(); err == nil {
-		imageDigest, err := image.Digest()
+	switch {
+	case desc.MediaType.IsIndex():
+		index, err := desc.ImageIndex()
 		if err != nil {
-			return "", time.Time{}, err
+			return nil, err
 		}
-		digestString := imageDigest.String()
-		cf, err := image.ConfigFile()
-		if err != nil {
-			return "", time.Time{}, err
+		platforms, err := common.PlatformImages(index, platform)
+		if err != nil || len(platforms) == 0 {
+			return nil, err
 		}
-		return digestString, cf.Created.Time, nil
-	} else if strings.Contains(err.Error(), "unsupported MediaType") && (desc.MediaType == types.DockerManifestSchema1 || desc.MediaType == types.DockerManifestSchema1Signed) {
+		image := &common.TaggedImage{Digest: desc.Digest.String(), Platforms: platforms}
+		for _, platformImage := range platforms {
+			if platformImage.Created.After(image.Created) {
+				image.Created = platformImage.Created
+			}
+		}
+		return image, nil
+	case desc.MediaType.IsSchema1():
 		//v1 schema need to parse the manifest
-		rawManifest, err := desc.RawManifest()
+		manifest, err := decodeV1Mafinset(desc.Manifest)
 		if err != nil {
-			return "", time.Time{}, err
+			return nil, err
+		}
+		if len(manifest.history) == 0 {
+			return nil, fmt.Errorf("failed to parse v1 manifest history")
 		}
-		manifest, err := decodeV1Mafinset(rawManifest)
+		cf := manifest.configFile()
+		if !platform.IsDefault() && !platform.Matches(cf.Platform()) {
+			return nil, nil
+		}
+		return &common.TaggedImage{Digest: desc.Digest.String(), Created: cf.Created.Time}, nil
+	case desc.MediaType.IsImage():
+		image, err := desc.Image()
 		if err != nil {
-			return "", time.Time{}, err
+			return nil, err
 		}
-		if len(manifest.history) == 0 {
-			return "", time.Time{}, fmt.Errorf("failed to parse v1 manifest history")
+		cf, err := image.ConfigFile()
+		if err != nil {
+			return nil, err
 		}
-		return desc.Digest.String(), manifest.history[0].v1Compatibility.created, nil
-	} else {
+		if !platform.IsDefault() && !platform.Matches(cf.Platform()) {
+			return nil, nil
+		}
+		return &common.TaggedImage{Digest: desc.Digest.String(), Created: cf.Created.Time}, nil
+	default:
 		//unknown schema
-		return "", time.Time{}, err
+		return nil, fmt.Errorf("unsupported MediaType: %s", desc.MediaType)
 	}
 }
 
-type tagInfo struct {
-	tags    []string
-	created time.Time
-	digest  string
-}
-type tagsInfo []*tagInfo
+type taggedImages []*common.TaggedImage
 
-func (ti tagsInfo) getByDigest(digest string) *tagInfo {
-	for _, tagInfo := range ti {
-		if tagInfo.digest == digest {
-			return tagInfo
+func (ti taggedImages) getByDigest(digest string) *common.TaggedImage {
+	for _, image := range ti {
+		if image.Digest == digest {
+			return image
 		}
 	}
 	return nil
 }
 
+// withContext returns a copy of options which binds the remote calls to ctx
+func withContext(ctx context.Context, options []remote.Option) []remote.Option {
+	return append(options[:len(options):len(options)], remote.WithContext(ctx))
+}
+
 func getRegistryProvider(registryName string) string {
 	if strings.Contains(registryName, ".dkr.ecr") {
 		return "ecr"
@@ -0,0 +1,157 @@
+package defaultregistry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRegistry(t *testing.T, handler http.Handler) (*DefaultRegistry, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	reg, err := NewRegistry(nil, &registry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	return reg.(*DefaultRegistry), server
+}
+
+func TestListPagination(t *testing.T) {
+	reg, server := newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.String() {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/my-repo/tags/list?n=2":
+			w.Header().Add("Link", `</v2/my-repo/tags/list?last=v2&n=2>; rel="next"`)
+			w.Write([]byte(`{"name":"my-repo","tags":["v1","v2"]}`))
+		case "/v2/my-repo/tags/list?last=v2&n=2":
+			w.Write([]byte(`{"name":"my-repo","tags":["v3"]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	tags, nextPage, err := reg.ListWithContext(ctx, "my-repo", common.MakePagination(2))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1", "v2"}, tags)
+	assert.Equal(t, &common.PaginationOption{Cursor: "v2", Size: 2}, nextPage)
+
+	tags, nextPage, err = reg.ListWithContext(ctx, "my-repo", *nextPage)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v3"}, tags)
+	assert.Nil(t, nextPage)
+}
+
+func TestListPageError(t *testing.T) {
+	reg, server := newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, _, err := reg.ListWithContext(context.Background(), "my-repo", common.MakePagination(2))
+	assert.Error(t, err)
+}
+
+func TestListTagFilter(t *testing.T) {
+	reg, server := newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.String() {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/my-repo/tags/list?n=3":
+			w.Header().Add("Link", `</v2/my-repo/tags/list?last=sha256-abc.sig&n=3>; rel="next"`)
+			w.Write([]byte(`{"name":"my-repo","tags":["pr-1","sha-abc","sha256-abc.sig"]}`))
+		case "/v2/my-repo/tags/list?last=sha256-abc.sig&n=3":
+			w.Write([]byte(`{"name":"my-repo","tags":["v1.0.0","v2.0.0"]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	reg.Cfg.WithTagFilter(&common.TagFilter{Exclude: []string{"pr-*", "sha-*"}, ExcludeCosignArtifacts: true})
+
+	//a fully filtered page still points to the next page
+	ctx := context.Background()
+	tags, nextPage, err := reg.ListWithContext(ctx, "my-repo", common.MakePagination(3))
+	assert.Nil(t, err)
+	assert.Empty(t, tags)
+	assert.Equal(t, &common.PaginationOption{Cursor: "sha256-abc.sig", Size: 3}, nextPage)
+
+	tags, nextPage, err = reg.ListWithContext(ctx, "my-repo", *nextPage)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v2.0.0"}, tags)
+	assert.Nil(t, nextPage)
+}
+
+func TestListPaginationWithAuthOption(t *testing.T) {
+	listRequests := 0
+	reg, server := newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/v2/my-repo/tags/list" {
+			listRequests++
+		}
+		switch r.URL.String() {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/my-repo/tags/list?n=1":
+			//the first page of a listing from a cursor
+			w.Header().Add("Link", `</v2/my-repo/tags/list?last=v1&n=1>; rel="next"`)
+			w.Write([]byte(`{"name":"my-repo","tags":["v1"]}`))
+		case "/v2/my-repo/tags/list?n=2":
+			w.Header().Add("Link", `</v2/my-repo/tags/list?last=v2&n=2>; rel="next"`)
+			w.Write([]byte(`{"name":"my-repo","tags":["v1","v2"]}`))
+		case "/v2/my-repo/tags/list?last=v2&n=2":
+			w.Header().Add("Link", `</v2/my-repo/tags/list?last=v4&n=2>; rel="next"`)
+			w.Write([]byte(`{"name":"my-repo","tags":["v3","v4"]}`))
+		case "/v2/my-repo/tags/list?last=v4&n=2", "/v2/my-repo/tags/list?last=v4-deleted&n=2":
+			w.Write([]byte(`{"name":"my-repo","tags":["v5"]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	//the registry has no credentials, they are only given by the options
+	withAuth := remote.WithAuth(&authn.Basic{Username: "user", Password: "secret"})
+	ctx := context.Background()
+	pages := [][]string{}
+	for pagination := common.MakePagination(2); ; {
+		tags, nextPage, err := reg.ListWithContext(ctx, "my-repo", pagination, withAuth)
+		assert.Nil(t, err)
+		pages = append(pages, tags)
+		if nextPage == nil {
+			break
+		}
+		pagination = *nextPage
+	}
+	assert.Equal(t, [][]string{{"v1", "v2"}, {"v3", "v4"}, {"v5"}}, pages)
+	//the pages are not listed again from the first one
+	assert.Equal(t, 5, listRequests)
+
+	//the registry lists the tags after a deleted cursor
+	tags, nextPage, err := reg.ListWithContext(ctx, "my-repo", common.PaginationOption{Cursor: "v4-deleted", Size: 2}, withAuth)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Equal(t, []string{"v5"}, tags)
+
+	_, _, err = reg.ListWithContext(ctx, "my-repo", common.MakePagination(2))
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package defaultregistry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// DeleteTag deletes a tag with DELETE /v2/<name>/manifests/<tag>, registries implementing only the
+// docker distribution API reject it (deleting the manifest digest removes all its tags instead)
+func (reg *DefaultRegistry) DeleteTag(ctx context.Context, repoName string, tag string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error) {
+	ref, err := common.MakeReference(repoName, tag, reg.Registry)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ref.(name.Tag); !ok {
+		return nil, fmt.Errorf("%s is not a tag", tag)
+	}
+	result, err := deleteResult(ctx, ref, repoName, tag, opts, options...)
+	if err != nil || result.DryRun {
+		return result, err
+	}
+	if err := remote.Delete(ref, withContext(ctx, options)...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteManifest deletes a manifest by digest with DELETE /v2/<name>/manifests/<digest>
+func (reg *DefaultRegistry) DeleteManifest(ctx context.Context, repoName string, digest string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error) {
+	ref, err := common.MakeReference(repoName, digest, reg.Registry)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ref.(name.Digest); !ok {
+		return nil, fmt.Errorf("%s is not a digest", digest)
+	}
+	result, err := deleteResult(ctx, ref, repoName, digest, opts, options...)
+	if err != nil || result.DryRun {
+		return result, err
+	}
+	if err := remote.Delete(ref, withContext(ctx, options)...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// deleteResult resolves the manifest a reference points to, failing if it does not exist
+func deleteResult(ctx context.Context, ref name.Reference, repoName, reference string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error) {
+	digest, err := headDigest(ctx, ref, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &common.DeleteResult{Repository: repoName, Reference: reference, Digest: digest, DryRun: opts.DryRun}, nil
+}
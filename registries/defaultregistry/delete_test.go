@@ -0,0 +1,57 @@
+package defaultregistry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelete(t *testing.T) {
+	reg, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.Registry)
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	image, err := random.Image(64, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("v1"), image))
+	assert.Nil(t, remote.Write(repo.Tag("v2"), image))
+	digest, err := image.Digest()
+	assert.Nil(t, err)
+
+	//dry run keeps the tag
+	result, err := reg.DeleteTag(ctx, "my-repo", "v1", common.DeleteOption{DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, &common.DeleteResult{Repository: "my-repo", Reference: "v1", Digest: digest.String(), DryRun: true}, result)
+	_, err = reg.Resolve(ctx, "my-repo", "v1")
+	assert.Nil(t, err)
+
+	result, err = reg.DeleteTag(ctx, "my-repo", "v1", common.DeleteOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, &common.DeleteResult{Repository: "my-repo", Reference: "v1", Digest: digest.String()}, result)
+	_, err = reg.Resolve(ctx, "my-repo", "v1")
+	assert.Error(t, err)
+	_, err = reg.Resolve(ctx, "my-repo", "v2")
+	assert.Nil(t, err)
+
+	_, err = reg.DeleteTag(ctx, "my-repo", "v1", common.DeleteOption{DryRun: true})
+	assert.Error(t, err)
+	_, err = reg.DeleteTag(ctx, "my-repo", digest.String(), common.DeleteOption{})
+	assert.Error(t, err)
+	_, err = reg.DeleteManifest(ctx, "my-repo", "v2", common.DeleteOption{})
+	assert.Error(t, err)
+
+	result, err = reg.DeleteManifest(ctx, "my-repo", digest.String(), common.DeleteOption{DryRun: true})
+	assert.Nil(t, err)
+	assert.True(t, result.DryRun)
+	result, err = reg.DeleteManifest(ctx, "my-repo", digest.String(), common.DeleteOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, digest.String(), result.Digest)
+	_, err = reg.DeleteManifest(ctx, "my-repo", digest.String(), common.DeleteOption{DryRun: true})
+	assert.Error(t, err)
+}
@@ -17,11 +17,19 @@ func decodeV1Mafinset(raw []byte) (*shortV1Manifest, error) {
 }
 
 type shortV1Manifest struct {
-	history history
+	architecture string
+	fsLayers     fsLayers
+	history      history
 }
 
 func (m *shortV1Manifest) UnmarshalJSONObject(dec *gojay.Decoder, key string) (err error) {
-	if key == "history" {
+	switch key {
+	case "architecture":
+		err = dec.String(&m.architecture)
+	case "fsLayers":
+		m.fsLayers = fsLayers{}
+		err = dec.DecodeArray(&m.fsLayers)
+	case "history":
 		m.history = history{}
 		err = dec.DecodeArray(&m.history)
 	}
@@ -29,6 +37,37 @@ func (m *shortV1Manifest) UnmarshalJSONObject(dec *gojay.Decoder, key string) (e
 }
 
 func (r *shortV1Manifest) NKeys() int {
+	return 3
+}
+
+// fsLayers holds the layers blob sums, the first one is the top layer
+type fsLayers []string
+
+func (l *fsLayers) UnmarshalJSONArray(dec *gojay.Decoder) error {
+	layer := fsLayer{}
+	if err := dec.Object(&layer); err != nil {
+		return err
+	}
+	*l = append(*l, layer.blobSum)
+	return nil
+}
+
+func (l *fsLayers) NKeys() int {
+	return 0
+}
+
+type fsLayer struct {
+	blobSum string
+}
+
+func (l *fsLayer) UnmarshalJSONObject(dec *gojay.Decoder, key string) (err error) {
+	if key == "blobSum" {
+		err = dec.String(&l.blobSum)
+	}
+	return err
+}
+
+func (l *fsLayer) NKeys() int {
 	return 1
 }
 
@@ -71,20 +110,77 @@ func (r *shortV1HistoryObj) NKeys() int {
 }
 
 type shortV1Compatibility struct {
-	created time.Time
+	created      time.Time
+	os           string
+	architecture string
+	config       shortV1Config
 }
 
 func (v1Comp *shortV1Compatibility) UnmarshalJSONObject(dec *gojay.Decoder, key string) (err error) {
-	if key == "created" {
+	switch key {
+	case "created":
 		var createdStr string
 		err = dec.String(&createdStr)
 		if err == nil {
 			v1Comp.created, err = time.Parse(time.RFC3339, createdStr)
 		}
+	case "os":
+		err = dec.String(&v1Comp.os)
+	case "architecture":
+		err = dec.String(&v1Comp.architecture)
+	case "config":
+		err = dec.Object(&v1Comp.config)
 	}
 	return err
 }
 
 func (n *shortV1Compatibility) NKeys() int {
-	return 1
+	return 4
+}
+
+type shortV1Config struct {
+	labels     labels
+	env        []string
+	entrypoint []string
+	cmd        []string
+	user       string
+	workingDir string
+}
+
+func (c *shortV1Config) UnmarshalJSONObject(dec *gojay.Decoder, key string) (err error) {
+	switch key {
+	case "Labels":
+		c.labels = labels{}
+		err = dec.Object(&c.labels)
+	case "Env":
+		err = dec.SliceString(&c.env)
+	case "Entrypoint":
+		err = dec.SliceString(&c.entrypoint)
+	case "Cmd":
+		err = dec.SliceString(&c.cmd)
+	case "User":
+		err = dec.String(&c.user)
+	case "WorkingDir":
+		err = dec.String(&c.workingDir)
+	}
+	return err
+}
+
+func (c *shortV1Config) NKeys() int {
+	return 6
+}
+
+type labels map[string]string
+
+func (l labels) UnmarshalJSONObject(dec *gojay.Decoder, key string) error {
+	var value string
+	if err := dec.String(&value); err != nil {
+		return err
+	}
+	l[key] = value
+	return nil
+}
+
+func (l labels) NKeys() int {
+	return 0
 }
@@ -0,0 +1,114 @@
+package defaultregistry
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/armosec/registryx/common"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// Inspect returns the manifest, configuration and layers of a tag or a digest in repoName.
+// For an index the images matching platform are listed and when a single platform is selected
+// its manifest, configuration and layers are returned along with the index digest
+func (reg *DefaultRegistry) Inspect(ctx context.Context, repoName string, reference string, platform common.PlatformOption, options ...remote.Option) (*common.ImageInspection, error) {
+	ref, err := common.MakeReference(repoName, reference, reg.Registry)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := remote.Get(ref, withContext(ctx, options)...)
+	if err != nil {
+		return nil, err
+	}
+	inspection := &common.ImageInspection{
+		Reference:   ref.String(),
+		MediaType:   desc.MediaType,
+		Digest:      desc.Digest.String(),
+		Size:        desc.Size,
+		RawManifest: desc.Manifest,
+	}
+	switch {
+	case desc.MediaType.IsIndex():
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, err
+		}
+		if inspection.Index, err = index.IndexManifest(); err != nil {
+			return nil, err
+		}
+		if inspection.Platforms, err = common.PlatformImages(index, platform); err != nil {
+			return nil, err
+		}
+		if platform.All || len(inspection.Platforms) == 0 {
+			break
+		}
+		hash, err := v1.NewHash(inspection.Platforms[0].Digest)
+		if err != nil {
+			return nil, err
+		}
+		image, err := index.Image(hash)
+		if err != nil {
+			return nil, err
+		}
+		if err := common.InspectImage(image, inspection); err != nil {
+			return nil, err
+		}
+	case desc.MediaType.IsSchema1():
+		manifest, err := decodeV1Mafinset(desc.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		inspection.Config = manifest.configFile()
+		inspection.Layers = manifest.layers()
+	case desc.MediaType.IsImage():
+		image, err := desc.Image()
+		if err != nil {
+			return nil, err
+		}
+		if err := common.InspectImage(image, inspection); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported manifest media type %s", desc.MediaType)
+	}
+	return inspection, nil
+}
+
+// configFile converts the top v1Compatibility history entry to an image config
+func (m *shortV1Manifest) configFile() *v1.ConfigFile {
+	cf := &v1.ConfigFile{Architecture: m.architecture}
+	if len(m.history) == 0 {
+		return cf
+	}
+	top := m.history[0].v1Compatibility
+	cf.Created = v1.Time{Time: top.created}
+	cf.OS = top.os
+	if top.architecture != "" {
+		cf.Architecture = top.architecture
+	}
+	cf.Config = v1.Config{
+		Labels:     top.config.labels,
+		Env:        top.config.env,
+		Entrypoint: top.config.entrypoint,
+		Cmd:        top.config.cmd,
+		User:       top.config.user,
+		WorkingDir: top.config.workingDir,
+	}
+	return cf
+}
+
+// layers returns the fsLayers ordered from the base layer (schema1 lists the top layer first)
+func (m *shortV1Manifest) layers() []v1.Descriptor {
+	layers := make([]v1.Descriptor, 0, len(m.fsLayers))
+	for _, blobSum := range slices.Backward(m.fsLayers) {
+		hash, err := v1.NewHash(blobSum)
+		if err != nil {
+			continue
+		}
+		layers = append(layers, v1.Descriptor{MediaType: types.DockerLayer, Digest: hash, Size: -1})
+	}
+	return layers
+}
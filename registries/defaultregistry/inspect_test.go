@@ -0,0 +1,114 @@
+package defaultregistry
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type rawManifest struct {
+	raw       []byte
+	mediaType types.MediaType
+}
+
+func (m rawManifest) RawManifest() ([]byte, error) {
+	return m.raw, nil
+}
+
+func (m rawManifest) MediaType() (types.MediaType, error) {
+	return m.mediaType, nil
+}
+
+const schema1Manifest = `{
+   "schemaVersion": 1,
+   "name": "my-repo",
+   "tag": "v1",
+   "architecture": "amd64",
+   "fsLayers": [
+      {"blobSum": "sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4"},
+      {"blobSum": "sha256:cc8567d70002e957612902a8e985ea129d831ebe04057d88fb644857caa45d11"}
+   ],
+   "history": [
+      {"v1Compatibility": "{\"architecture\":\"amd64\",\"config\":{\"Env\":[\"PATH=/usr/bin\"],\"Cmd\":[\"sh\"],\"Entrypoint\":null,\"Labels\":{\"maintainer\":\"armo\"}},\"container_config\":{\"Labels\":{\"other\":\"x\"}},\"created\":\"2016-06-08T00:01:22Z\",\"id\":\"1\",\"os\":\"linux\"}"},
+      {"v1Compatibility": "{\"id\":\"2\",\"created\":\"2016-06-07T00:01:22Z\"}"}
+   ]
+}`
+
+func newInMemoryRegistry(t *testing.T) (*DefaultRegistry, *httptest.Server) {
+	server := httptest.NewServer(registry.New())
+	reg, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	iRegistry, err := NewRegistry(nil, &reg, common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	return iRegistry.(*DefaultRegistry), server
+}
+
+func TestInspect(t *testing.T) {
+	reg, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.Registry)
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	//schema2 image
+	image, err := random.Image(64, 2)
+	assert.Nil(t, err)
+	image, err = mutate.Config(image, v1.Config{Labels: map[string]string{"app": "x"}})
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("v2"), image))
+	digest, err := image.Digest()
+	assert.Nil(t, err)
+
+	inspection, err := reg.Inspect(ctx, "my-repo", "v2", common.PlatformOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, digest.String(), inspection.Digest)
+	assert.Equal(t, types.DockerManifestSchema2, inspection.MediaType)
+	assert.Equal(t, map[string]string{"app": "x"}, inspection.Config.Config.Labels)
+	assert.Len(t, inspection.Layers, 2)
+	assert.False(t, inspection.IsIndex())
+
+	//by digest
+	inspection, err = reg.Inspect(ctx, "my-repo", digest.String(), common.PlatformOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, digest.String(), inspection.Digest)
+
+	//index
+	index, err := random.Index(64, 1, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.WriteIndex(repo.Tag("multi"), index))
+	inspection, err = reg.Inspect(ctx, "my-repo", "multi", common.PlatformOption{})
+	assert.Nil(t, err)
+	assert.True(t, inspection.IsIndex())
+	assert.Len(t, inspection.Index.Manifests, 2)
+	//random indexes have no platforms
+	assert.Empty(t, inspection.Platforms)
+	assert.Nil(t, inspection.Config)
+
+	//schema1
+	assert.Nil(t, remote.Put(repo.Tag("v1"), rawManifest{raw: []byte(schema1Manifest), mediaType: types.DockerManifestSchema1}))
+	inspection, err = reg.Inspect(ctx, "my-repo", "v1", common.PlatformOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, types.DockerManifestSchema1, inspection.MediaType)
+	assert.Equal(t, "linux", inspection.Config.OS)
+	assert.Equal(t, "amd64", inspection.Config.Architecture)
+	assert.Equal(t, "2016-06-08T00:01:22Z", inspection.Config.Created.UTC().Format("2006-01-02T15:04:05Z"))
+	assert.Equal(t, map[string]string{"maintainer": "armo"}, inspection.Config.Config.Labels)
+	assert.Equal(t, []string{"PATH=/usr/bin"}, inspection.Config.Config.Env)
+	assert.Equal(t, []string{"sh"}, inspection.Config.Config.Cmd)
+	assert.Len(t, inspection.Layers, 2)
+	assert.Equal(t, "sha256:cc8567d70002e957612902a8e985ea129d831ebe04057d88fb644857caa45d11", inspection.Layers[0].Digest.String())
+
+	_, err = reg.Inspect(ctx, "my-repo", "missing", common.PlatformOption{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package defaultregistry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func platformImage(t *testing.T, platform v1.Platform, created time.Time) v1.Image {
+	image, err := random.Image(32, 1)
+	assert.Nil(t, err)
+	cf, err := image.ConfigFile()
+	assert.Nil(t, err)
+	cf = cf.DeepCopy()
+	cf.OS = platform.OS
+	cf.Architecture = platform.Architecture
+	cf.Variant = platform.Variant
+	cf.Created = v1.Time{Time: created}
+	image, err = mutate.ConfigFile(image, cf)
+	assert.Nil(t, err)
+	return image
+}
+
+func platformIndex(images ...v1.Image) v1.ImageIndex {
+	addenda := []mutate.IndexAddendum{}
+	for _, image := range images {
+		cf, _ := image.ConfigFile()
+		addenda = append(addenda, mutate.IndexAddendum{Add: image, Descriptor: v1.Descriptor{Platform: cf.Platform()}})
+	}
+	return mutate.AppendManifests(empty.Index, addenda...)
+}
+
+func TestGetLatestImagesPlatforms(t *testing.T) {
+	reg, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.Registry)
+	assert.Nil(t, err)
+
+	amd64 := v1.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := v1.Platform{OS: "linux", Architecture: "arm64"}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, remote.Write(repo.Tag("amd"), platformImage(t, amd64, base)))
+	multi := platformIndex(platformImage(t, amd64, base.Add(time.Hour)), platformImage(t, arm64, base.Add(3*time.Hour)))
+	assert.Nil(t, remote.WriteIndex(repo.Tag("multi"), multi))
+	assert.Nil(t, remote.WriteIndex(repo.Tag("multi-alias"), multi))
+	armOnly := platformIndex(platformImage(t, arm64, base.Add(2*time.Hour)))
+	assert.Nil(t, remote.WriteIndex(repo.Tag("arm"), armOnly))
+	multiDigest, err := multi.Digest()
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	//default platform: arm only index is skipped and the index digest is reported
+	images, err := reg.GetLatestImages(ctx, "my-repo", 5, common.LatestTagsOption{})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"multi-alias", "multi"}, images[0].Tags)
+	assert.Equal(t, multiDigest.String(), images[0].Digest)
+	assert.Len(t, images[0].Platforms, 1)
+	assert.Equal(t, amd64, images[0].Platforms[0].Platform)
+	assert.Equal(t, []string{"amd"}, images[1].Tags)
+
+	tags, err := reg.GetLatestTagsWithContext(ctx, "my-repo", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"multi-alias,multi", "amd"}, tags)
+
+	//arm64 only
+	images, err = reg.GetLatestImages(ctx, "my-repo", 5, common.LatestTagsOption{Platform: common.PlatformOption{OS: "linux", Architecture: "arm64"}})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"multi-alias", "multi"}, images[0].Tags)
+	assert.Equal(t, base.Add(3*time.Hour), images[0].Created.UTC())
+	assert.Equal(t, []string{"arm"}, images[1].Tags)
+
+	//all platforms
+	images, err = reg.GetLatestImages(ctx, "my-repo", 5, common.LatestTagsOption{Platform: common.AllPlatforms()})
+	assert.Nil(t, err)
+	assert.Len(t, images, 3)
+	assert.Len(t, images[0].Platforms, 2)
+	assert.Equal(t, []string{"arm"}, images[1].Tags)
+	assert.Equal(t, []string{"amd"}, images[2].Tags)
+
+	//inspect a single platform of the index
+	inspection, err := reg.Inspect(ctx, "my-repo", "multi", common.PlatformOption{OS: "linux", Architecture: "arm64"})
+	assert.Nil(t, err)
+	assert.Equal(t, multiDigest.String(), inspection.Digest)
+	assert.Len(t, inspection.Platforms, 1)
+	assert.Equal(t, "arm64", inspection.Config.Architecture)
+}
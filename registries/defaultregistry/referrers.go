@@ -0,0 +1,74 @@
+package defaultregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// Referrers lists the artifacts attached to digest in repoName.
+// It uses the OCI 1.1 referrers API (falling back to the sha256-<hex> referrers tag schema)
+// and adds the cosign tag based artifacts (sha256-<hex>.sig, .att and .sbom)
+func (reg *DefaultRegistry) Referrers(ctx context.Context, repoName string, digest string, options ...remote.Option) ([]common.Referrer, error) {
+	ref, err := common.MakeReference(repoName, digest, reg.Registry)
+	if err != nil {
+		return nil, err
+	}
+	digestRef, ok := ref.(name.Digest)
+	if !ok {
+		return nil, fmt.Errorf("referrers require a digest, got %s", digest)
+	}
+	options = withContext(ctx, options)
+	index, err := remote.Referrers(digestRef, options...)
+	if err != nil {
+		return nil, err
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	referrers := []common.Referrer{}
+	for _, manifest := range indexManifest.Manifests {
+		referrers = append(referrers, common.Referrer{
+			Digest:       manifest.Digest.String(),
+			MediaType:    manifest.MediaType,
+			ArtifactType: manifest.ArtifactType,
+			Size:         manifest.Size,
+			Annotations:  manifest.Annotations,
+		})
+	}
+	for _, suffix := range common.CosignTagSuffixes {
+		tag := digestRef.Context().Tag(common.CosignTag(digestRef.DigestStr(), suffix))
+		referrer, err := cosignReferrer(tag, suffix, options...)
+		if err != nil {
+			return nil, err
+		}
+		if referrer != nil {
+			referrers = append(referrers, *referrer)
+		}
+	}
+	return referrers, nil
+}
+
+// cosignReferrer returns the cosign artifact stored in tag or nil if it does not exist
+func cosignReferrer(tag name.Tag, suffix string, options ...remote.Option) (*common.Referrer, error) {
+	desc, err := remote.Get(tag, options...)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return common.CosignReferrer(desc.Descriptor, desc.Manifest, tag.TagStr(), suffix)
+}
+
+func isNotFound(err error) bool {
+	var transportErr *transport.Error
+	return errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,64 @@
+package defaultregistry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferrers(t *testing.T) {
+	reg, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.Registry)
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	image, err := random.Image(64, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("v1"), image))
+	digest, err := image.Digest()
+	assert.Nil(t, err)
+	descriptor, err := remote.Head(repo.Digest(digest.String()))
+	assert.Nil(t, err)
+
+	referrers, err := reg.Referrers(ctx, "my-repo", digest.String())
+	assert.Nil(t, err)
+	assert.Empty(t, referrers)
+
+	//OCI 1.1 SBOM referrer
+	sbom := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	sbom = mutate.ConfigMediaType(sbom, common.SPDXMediaType)
+	sbom = mutate.Subject(sbom, *descriptor).(v1.Image)
+	sbomDigest, err := sbom.Digest()
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Digest(sbomDigest.String()), sbom))
+
+	//cosign tag based signature
+	signature, err := random.Image(16, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag(common.CosignTag(digest.String(), common.CosignSignatureTagSuffix)), signature))
+	signatureDigest, err := signature.Digest()
+	assert.Nil(t, err)
+
+	referrers, err = reg.Referrers(ctx, "my-repo", digest.String())
+	assert.Nil(t, err)
+	assert.Len(t, referrers, 2)
+	assert.Equal(t, sbomDigest.String(), referrers[0].Digest)
+	assert.Equal(t, common.SPDXMediaType, referrers[0].ArtifactType)
+	assert.True(t, referrers[0].IsSBOM())
+	assert.False(t, referrers[0].IsSignature())
+	assert.Equal(t, signatureDigest.String(), referrers[1].Digest)
+	assert.Equal(t, "sha256-"+digest.Hex+".sig", referrers[1].Tag)
+	assert.True(t, referrers[1].IsSignature())
+
+	_, err = reg.Referrers(ctx, "my-repo", "v1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package defaultregistry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// Resolve returns the manifest digest a tag points to using a HEAD request,
+// registries which do not send the Docker-Content-Digest header (or do not support HEAD) are resolved with a GET request
+func (reg *DefaultRegistry) Resolve(ctx context.Context, repoName string, reference string, options ...remote.Option) (string, error) {
+	ref, err := common.MakeReference(repoName, reference, reg.Registry)
+	if err != nil {
+		return "", err
+	}
+	//already immutable
+	if digest, ok := ref.(name.Digest); ok {
+		return digest.DigestStr(), nil
+	}
+	return headDigest(ctx, ref, options...)
+}
+
+// headDigest returns the digest of an existing manifest with a HEAD request, falling back to a GET request
+func headDigest(ctx context.Context, ref name.Reference, options ...remote.Option) (string, error) {
+	options = withContext(ctx, options)
+	desc, err := remote.Head(ref, options...)
+	if err == nil {
+		return desc.Digest.String(), nil
+	}
+	if !shouldFallbackToGet(ctx, err) {
+		return "", err
+	}
+	fullDesc, err := remote.Get(ref, options...)
+	if err != nil {
+		return "", err
+	}
+	return fullDesc.Digest.String(), nil
+}
+
+// shouldFallbackToGet reports whether a failed HEAD request may succeed as a GET request
+func shouldFallbackToGet(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		switch transportErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return false
+		}
+	}
+	return true
+}
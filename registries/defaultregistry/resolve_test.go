@@ -0,0 +1,81 @@
+package defaultregistry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	methods := []string{}
+	withoutDigestHeader := false
+	inMemory := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") {
+			methods = append(methods, r.Method)
+		}
+		if withoutDigestHeader && r.Method == http.MethodHead {
+			recorder := httptest.NewRecorder()
+			inMemory.ServeHTTP(recorder, r)
+			for key, values := range recorder.Header() {
+				if key != "Docker-Content-Digest" {
+					w.Header()[key] = values
+				}
+			}
+			w.WriteHeader(recorder.Code)
+			return
+		}
+		inMemory.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	reg, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	iRegistry, err := NewRegistry(nil, &reg, common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+
+	image, err := random.Image(64, 1)
+	assert.Nil(t, err)
+	repo, err := common.MakeRepoWithRegistry("my-repo", &reg)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("v1"), image))
+	digest, err := image.Digest()
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	//resolved with a single HEAD request
+	methods = []string{}
+	resolved, err := iRegistry.Resolve(ctx, "my-repo", "v1")
+	assert.Nil(t, err)
+	assert.Equal(t, digest.String(), resolved)
+	assert.Equal(t, []string{http.MethodHead}, methods)
+
+	//falls back to GET when the digest header is missing
+	methods = []string{}
+	withoutDigestHeader = true
+	resolved, err = iRegistry.Resolve(ctx, "my-repo", "v1")
+	assert.Nil(t, err)
+	assert.Equal(t, digest.String(), resolved)
+	assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods)
+
+	//no fallback for missing tags
+	methods = []string{}
+	_, err = iRegistry.Resolve(ctx, "my-repo", "missing")
+	assert.Error(t, err)
+	assert.Equal(t, []string{http.MethodHead}, methods)
+
+	//digests are not requested at all
+	methods = []string{}
+	resolved, err = iRegistry.Resolve(ctx, "my-repo", digest.String())
+	assert.Nil(t, err)
+	assert.Equal(t, digest.String(), resolved)
+	assert.Empty(t, methods)
+}
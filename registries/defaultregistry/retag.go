@@ -0,0 +1,34 @@
+package defaultregistry
+
+import (
+	"context"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Retag tags the manifest a tag or a digest points to with newTag, without pulling the layers.
+// The manifest is fetched by digest and PUT under the new tag, it returns the tagged digest
+func (reg *DefaultRegistry) Retag(ctx context.Context, repoName string, reference string, newTag string, options ...remote.Option) (string, error) {
+	digest, err := reg.This.Resolve(ctx, repoName, reference, options...)
+	if err != nil {
+		return "", err
+	}
+	ref, err := common.MakeReference(repoName, digest, reg.Registry)
+	if err != nil {
+		return "", err
+	}
+	target, err := common.MakeReference(repoName, newTag, reg.Registry)
+	if err != nil {
+		return "", err
+	}
+	options = withContext(ctx, options)
+	desc, err := remote.Get(ref, options...)
+	if err != nil {
+		return "", err
+	}
+	if err := remote.Put(target, desc, options...); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
@@ -0,0 +1,48 @@
+package defaultregistry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetag(t *testing.T) {
+	reg, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.Registry)
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	image, err := random.Image(64, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("sha-abc"), image))
+	digest, err := image.Digest()
+	assert.Nil(t, err)
+
+	retagged, err := reg.Retag(ctx, "my-repo", "sha-abc", "1.4.2")
+	assert.Nil(t, err)
+	assert.Equal(t, digest.String(), retagged)
+	resolved, err := reg.Resolve(ctx, "my-repo", "1.4.2")
+	assert.Nil(t, err)
+	assert.Equal(t, digest.String(), resolved)
+
+	//by digest
+	index, err := random.Index(64, 1, 2)
+	assert.Nil(t, err)
+	indexDigest, err := index.Digest()
+	assert.Nil(t, err)
+	assert.Nil(t, remote.WriteIndex(repo.Digest(indexDigest.String()), index))
+	retagged, err = reg.Retag(ctx, "my-repo", indexDigest.String(), "multi")
+	assert.Nil(t, err)
+	assert.Equal(t, indexDigest.String(), retagged)
+	desc, err := remote.Head(repo.Tag("multi"))
+	assert.Nil(t, err)
+	assert.True(t, desc.MediaType.IsIndex())
+
+	_, err = reg.Retag(ctx, "my-repo", "missing", "1.4.3")
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package defaultregistry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLatestImagesStrategy(t *testing.T) {
+	reg, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.Registry)
+	assert.Nil(t, err)
+	amd64 := v1.Platform{OS: "linux", Architecture: "amd64"}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	//a hotfix of an old release is the most recently built image
+	for tag, created := range map[string]time.Time{
+		"1.4.2":  base,
+		"1.5.0":  base.Add(time.Hour),
+		"1.4.3":  base.Add(2 * time.Hour),
+		"pr-123": base.Add(3 * time.Hour),
+	} {
+		assert.Nil(t, remote.Write(repo.Tag(tag), platformImage(t, amd64, created)))
+	}
+	assert.Nil(t, remote.Write(repo.Tag("latest"), platformImage(t, amd64, base)))
+	ctx := context.Background()
+
+	//default selection returns latest right away
+	images, err := reg.GetLatestImages(ctx, "my-repo", 1, common.LatestTagsOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"latest"}, images[0].Tags)
+
+	images, err = reg.GetLatestImages(ctx, "my-repo", 1, common.LatestTagsOption{Strategy: common.CreatedStrategy()})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pr-123"}, images[0].Tags)
+
+	images, err = reg.GetLatestImages(ctx, "my-repo", 2, common.LatestTagsOption{Strategy: common.SemverStrategy()})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"1.5.0"}, images[0].Tags)
+	assert.Equal(t, []string{"1.4.3"}, images[1].Tags)
+	assert.Equal(t, base.Add(2*time.Hour), images[1].Created.UTC())
+
+	constraint, err := common.SemverConstraintStrategy("~1.4")
+	assert.Nil(t, err)
+	images, err = reg.GetLatestImages(ctx, "my-repo", 1, common.LatestTagsOption{Strategy: constraint})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.4.3"}, images[0].Tags)
+
+	calver := common.CalverStrategy()
+	images, err = reg.GetLatestImages(ctx, "my-repo", 1, common.LatestTagsOption{Strategy: calver})
+	assert.Nil(t, err)
+	assert.Empty(t, images)
+}
+
+func TestGetLatestImagesTagFilter(t *testing.T) {
+	reg, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.Registry)
+	assert.Nil(t, err)
+	amd64 := v1.Platform{OS: "linux", Architecture: "amd64"}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, remote.Write(repo.Tag("1.0.0"), platformImage(t, amd64, base)))
+	assert.Nil(t, remote.Write(repo.Tag("pr-123"), platformImage(t, amd64, base.Add(time.Hour))))
+	assert.Nil(t, remote.Write(repo.Tag("latest"), platformImage(t, amd64, base.Add(time.Hour))))
+	//not an image, fetching it would fail
+	assert.Nil(t, remote.Put(repo.Tag("sha256-abc.sig"), rawManifest{raw: []byte(`{}`), mediaType: "application/vnd.unknown"}))
+	ctx := context.Background()
+
+	_, err = reg.GetLatestImages(ctx, "my-repo", 2, common.LatestTagsOption{})
+	assert.Error(t, err)
+
+	filter := &common.TagFilter{Exclude: []string{"pr-*", "latest"}, ExcludeCosignArtifacts: true}
+	images, err := reg.GetLatestImages(ctx, "my-repo", 1, common.LatestTagsOption{Filter: filter})
+	assert.Nil(t, err)
+	assert.Len(t, images, 1)
+	assert.Equal(t, []string{"1.0.0"}, images[0].Tags)
+
+	//registry level filter
+	reg.Cfg.WithTagFilter(filter)
+	tags, err := reg.GetLatestTags("my-repo", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0"}, tags)
+}
+
+func TestGetLatestImagesStrategyPages(t *testing.T) {
+	//the in-memory registry pages tags without linking the next page
+	inMemory := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/tags/list") {
+			inMemory.ServeHTTP(w, r)
+			return
+		}
+		recorder := httptest.NewRecorder()
+		inMemory.ServeHTTP(recorder, r)
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+		if n := r.URL.Query().Get("n"); n == strconv.Itoa(len(page.Tags)) {
+			w.Header().Add("Link", fmt.Sprintf(`<%s?last=%s&n=%s>; rel="next"`, r.URL.Path, page.Tags[len(page.Tags)-1], n))
+		}
+		w.Write(recorder.Body.Bytes())
+	}))
+	defer server.Close()
+	registryName, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	iRegistry, err := NewRegistry(nil, &registryName, common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	reg := iRegistry.(*DefaultRegistry)
+	pageSize := 2
+	reg.MaxPageSize = &pageSize
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.Registry)
+	assert.Nil(t, err)
+	amd64 := v1.Platform{OS: "linux", Architecture: "amd64"}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	//the newest release is tagged twice on different pages
+	release := platformImage(t, amd64, base.Add(3*time.Hour))
+	for tag, image := range map[string]v1.Image{
+		"1.0.0": platformImage(t, amd64, base),
+		"1.1.0": platformImage(t, amd64, base.Add(time.Hour)),
+		"1.2.0": platformImage(t, amd64, base.Add(2*time.Hour)),
+		"1.3.0": release,
+		"2.0.0": release,
+	} {
+		assert.Nil(t, remote.Write(repo.Tag(tag), image))
+	}
+	ctx := context.Background()
+
+	images, err := reg.GetLatestImages(ctx, "my-repo", 2, common.LatestTagsOption{Strategy: common.SemverStrategy()})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"2.0.0", "1.3.0"}, images[0].Tags)
+	assert.Equal(t, []string{"1.2.0"}, images[1].Tags)
+
+	images, err = reg.GetLatestImages(ctx, "my-repo", 1, common.LatestTagsOption{Strategy: common.SemverStrategy()})
+	assert.Nil(t, err)
+	assert.Len(t, images, 1)
+	assert.Equal(t, []string{"2.0.0"}, images[0].Tags)
+
+	images, err = reg.GetLatestImages(ctx, "my-repo", 0, common.LatestTagsOption{Strategy: common.SemverStrategy()})
+	assert.Nil(t, err)
+	assert.Empty(t, images)
+}
@@ -5,12 +5,17 @@ import (
 	"github.com/armosec/registryx/interfaces"
 	"github.com/armosec/registryx/registries/defaultregistry"
 	"github.com/armosec/registryx/registries/harbor"
+	"github.com/armosec/registryx/registries/ocilayout"
 	"github.com/armosec/registryx/registries/quay"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 )
 
 func Factory(auth *authn.AuthConfig, registryName string, registryOptions *common.RegistryOptions) (interfaces.IRegistry, error) {
+	//an OCI layout is a directory, not a registry host
+	if registryOptions != nil && registryOptions.Kind() == common.OCILayout {
+		return ocilayout.NewLayoutRegistry(registryName, registryOptions)
+	}
 	kind, registry, err := makeRegistry(registryOptions, registryName)
 	if err != nil {
 		return nil, err
@@ -3,11 +3,15 @@ package registries
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/armosec/registryx/common"
 	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries/ocilayout"
 	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/stretchr/testify/assert"
 )
@@ -115,3 +119,20 @@ func TestManyTagsWithOneDepth(t *testing.T) {
 	assert.Equal(t, 1, len(tags))
 	assert.Equal(t, "latest", tags[0])
 }
+
+func TestOCILayout(t *testing.T) {
+	dir := t.TempDir()
+	_, err := layout.Write(dir, empty.Index)
+	assert.Nil(t, err)
+	kind, err := common.GetRegistryKind("OCI-Layout")
+	assert.Nil(t, err)
+	reg, err := Factory(nil, dir, common.MakeRegistryOptions(false, false, false, "", "", "", kind))
+	assert.Nil(t, err)
+	assert.IsType(t, &ocilayout.LayoutRegistry{}, reg)
+	repositories, _, err := reg.Catalog(context.Background(), common.NoPaginationOption(), common.CatalogOption{}, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, repositories)
+
+	_, err = Factory(nil, filepath.Join(dir, "missing"), common.MakeRegistryOptions(false, false, false, "", "", "", kind))
+	assert.Error(t, err)
+}
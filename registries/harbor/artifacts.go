@@ -0,0 +1,277 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// harbor artifact types
+const (
+	ArtifactTypeImage = "IMAGE"
+	ArtifactTypeChart = "CHART"
+	ArtifactTypeCNAB  = "CNAB"
+)
+
+// harbor accessory types
+const (
+	AccessoryCosignSignature   = "signature.cosign"
+	AccessoryNotationSignature = "signature.notation"
+	AccessorySBOM              = "harbor.sbom"
+)
+
+// Artifact is an artifact of the harbor artifacts API with its tags, labels and accessories
+type Artifact struct {
+	ID                int64               `json:"id"`
+	Digest            string              `json:"digest"`
+	Type              string              `json:"type"`
+	MediaType         string              `json:"media_type"`
+	ManifestMediaType string              `json:"manifest_media_type"`
+	Size              int64               `json:"size"`
+	PushTime          time.Time           `json:"push_time"`
+	PullTime          time.Time           `json:"pull_time"` // zero if never pulled
+	Tags              []ArtifactTag       `json:"tags"`
+	Labels            []Label             `json:"labels"`
+	Accessories       []Accessory         `json:"accessories"`
+	References        []ArtifactReference `json:"references"` // the child manifests of an index
+	ExtraAttrs        ArtifactExtraAttrs  `json:"extra_attrs"`
+	// ScanOverview is the last scan of the artifact by report mime type, only set by Artifact
+	ScanOverview map[string]ScanOverview `json:"scan_overview"`
+}
+
+type ArtifactTag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	PushTime  time.Time `json:"push_time"`
+	PullTime  time.Time `json:"pull_time"`
+	Immutable bool      `json:"immutable"`
+}
+
+type Label struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Color       string `json:"color"`
+	Scope       string `json:"scope"` // "g" for global labels, "p" for project labels
+}
+
+// Accessory is an artifact attached to another one (a signature or an SBOM)
+type Accessory struct {
+	ID                    int64     `json:"id"`
+	Digest                string    `json:"digest"`
+	Type                  string    `json:"type"`
+	Size                  int64     `json:"size"`
+	SubjectArtifactDigest string    `json:"subject_artifact_digest"`
+	CreationTime          time.Time `json:"creation_time"`
+}
+
+type ArtifactReference struct {
+	ChildDigest string       `json:"child_digest"`
+	Platform    *v1.Platform `json:"platform"`
+}
+
+// ArtifactExtraAttrs are the image configuration attributes harbor extracts for IMAGE artifacts
+type ArtifactExtraAttrs struct {
+	Architecture string    `json:"architecture"`
+	OS           string    `json:"os"`
+	Created      time.Time `json:"created"`
+	Author       string    `json:"author"`
+}
+
+// TagNames returns the names of the artifact tags
+func (a *Artifact) TagNames() []string {
+	names := make([]string, 0, len(a.Tags))
+	for _, tag := range a.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// IsSigned reports whether the artifact has a cosign or a notation signature accessory
+func (a *Artifact) IsSigned() bool {
+	for _, accessory := range a.Accessories {
+		if accessory.Type == AccessoryCosignSignature || accessory.Type == AccessoryNotationSignature {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSBOM reports whether the artifact has an SBOM accessory
+func (a *Artifact) HasSBOM() bool {
+	for _, accessory := range a.Accessories {
+		if accessory.Type == AccessorySBOM {
+			return true
+		}
+	}
+	return false
+}
+
+// Artifacts lists a page of the artifacts of repoName ("<project>/<repository>") with their tags, labels and accessories,
+// the cursor is the page number
+func (h *HarborRegistry) Artifacts(ctx context.Context, repoName string, pagination common.PaginationOption) ([]Artifact, *common.PaginationOption, error) {
+	if pagination.Cursor == "" {
+		pagination.Cursor = "1"
+	} else if _, err := strconv.Atoi(pagination.Cursor); err != nil {
+		return nil, nil, fmt.Errorf("invalid pagination, cursor must be an integer")
+	}
+	uri, err := h.artifactsURL(repoName)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := artifactsQuery()
+	if pagination.Size > 0 {
+		query.Add("page_size", strconv.Itoa(pagination.Size))
+		query.Add("page", pagination.Cursor)
+	}
+	artifacts := []Artifact{}
+	res, err := h.getJSON(ctx, uri+"?"+query.Encode(), &artifacts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pagination.Size == 0 {
+		return artifacts, nil, nil
+	}
+	nextPagination, err := getNextPageOption(res)
+	return artifacts, nextPagination, err
+}
+
+// Artifact returns the artifact of a tag or a digest of repoName with its scan overview
+func (h *HarborRegistry) Artifact(ctx context.Context, repoName string, reference string) (*Artifact, error) {
+	uri, err := h.artifactsURL(repoName, reference)
+	if err != nil {
+		return nil, err
+	}
+	query := artifactsQuery()
+	query.Add("with_scan_overview", "true")
+	artifact := &Artifact{}
+	if _, err := h.getJSON(ctx, uri+"?"+query.Encode(), artifact); err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+func artifactsQuery() url.Values {
+	query := url.Values{}
+	query.Add("with_tag", "true")
+	query.Add("with_label", "true")
+	query.Add("with_accessory", "true")
+	return query
+}
+
+// getJSON decodes the response of an authenticated harbor API GET request to v
+func (h *HarborRegistry) getJSON(ctx context.Context, uri string, v any) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	h.addAuthHeader(req)
+	//harbor only returns the scan overviews and the vulnerability reports of the accepted mime types
+	req.Header.Set("X-Accept-Vulnerabilities", strings.Join(vulnerabilityReportMimeTypes, ", "))
+	res, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := transport.CheckError(res, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return res, json.NewDecoder(res.Body).Decode(v)
+}
+
+// GetLatestImages returns the latest images of repoName ranked by their push time (or by opts.Strategy)
+// from the harbor artifacts API, no manifest or image configuration is downloaded.
+// Registries without the artifacts API (harbor v1) fall back to the default implementation, as do strategies ranking by creation time
+// since harbor reports no creation time for index artifacts
+func (h *HarborRegistry) GetLatestImages(ctx context.Context, repoName string, depth int, opts common.LatestTagsOption, options ...remote.Option) ([]common.TaggedImage, error) {
+	if opts.Strategy != nil && !common.RanksByPushTime(opts.Strategy) {
+		return h.DefaultRegistry.GetLatestImages(ctx, repoName, depth, opts, options...)
+	}
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = common.PushedStrategy()
+	}
+	latestImages := []*common.TaggedImage{}
+	for artifacts, nextPage, err := h.Artifacts(ctx, repoName, common.MakePagination(h.GetMaxPageSize())); ; artifacts, nextPage, err = h.Artifacts(ctx, repoName, *nextPage) {
+		if err != nil {
+			var transportErr *transport.Error
+			if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound && len(latestImages) == 0 {
+				return h.DefaultRegistry.GetLatestImages(ctx, repoName, depth, opts, options...)
+			}
+			return nil, err
+		}
+		for i := range artifacts {
+			if image := taggedImage(&artifacts[i], opts.Platform, func(tag string) bool {
+				return strategy.Accepts(tag) && h.Cfg.TagFilter().Matches(tag) && opts.Filter.Matches(tag)
+			}); image != nil {
+				latestImages = append(latestImages, image)
+			}
+		}
+		if nextPage == nil {
+			break
+		}
+	}
+	sort.SliceStable(latestImages, func(i, j int) bool {
+		return strategy.NewerImage(latestImages[i], latestImages[j])
+	})
+	images := []common.TaggedImage{}
+	for _, image := range latestImages[:min(depth, len(latestImages))] {
+		common.SortImageTags(image.Tags)
+		images = append(images, *image)
+	}
+	return images, nil
+}
+
+// taggedImage converts an artifact to an image with its accepted tags, it returns nil for untagged artifacts,
+// for other artifact types (charts, CNAB) and for artifacts without an image of the selected platform
+func taggedImage(artifact *Artifact, platform common.PlatformOption, accepts func(tag string) bool) *common.TaggedImage {
+	if artifact.Type != "" && artifact.Type != ArtifactTypeImage {
+		return nil
+	}
+	image := &common.TaggedImage{Digest: artifact.Digest, Pushed: artifact.PushTime, Created: artifact.ExtraAttrs.Created}
+	for _, tag := range artifact.TagNames() {
+		if accepts(tag) {
+			image.Tags = append(image.Tags, tag)
+		}
+	}
+	if len(image.Tags) == 0 {
+		return nil
+	}
+	if len(artifact.References) == 0 {
+		artifactPlatform := &v1.Platform{OS: artifact.ExtraAttrs.OS, Architecture: artifact.ExtraAttrs.Architecture}
+		if !platform.IsDefault() && !platform.Matches(artifactPlatform) {
+			return nil
+		}
+		return image
+	}
+	for _, reference := range artifact.References {
+		if !platform.Matches(reference.Platform) {
+			continue
+		}
+		//harbor reports a null platform for children without one
+		platformImage := common.PlatformImage{Digest: reference.ChildDigest}
+		if reference.Platform != nil {
+			platformImage.Platform = *reference.Platform
+		}
+		image.Platforms = append(image.Platforms, platformImage)
+		if !platform.All {
+			break
+		}
+	}
+	if len(image.Platforms) == 0 {
+		return nil
+	}
+	return image
+}
@@ -0,0 +1,232 @@
+package harbor
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed fixtures/artifactsResponse.json
+var artifactsResponseBytes []byte
+
+const artifactsPath = "/api/v2.0/projects/my-project/repositories/team%252Fapp/artifacts"
+
+func newTestHarbor(t *testing.T, handler http.HandlerFunc) (*HarborRegistry, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	harbor, err := NewHarborRegistry(&authn.AuthConfig{Username: "admin", Password: "Harbor12345"}, &registry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Harbor))
+	assert.Nil(t, err)
+	return harbor.(*HarborRegistry), server
+}
+
+func TestArtifacts(t *testing.T) {
+	harbor, server := newTestHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Basic YWRtaW46SGFyYm9yMTIzNDU=", r.Header.Get("Authorization"))
+		switch r.URL.EscapedPath() {
+		case artifactsPath:
+			assert.Equal(t, "true", r.URL.Query().Get("with_tag"))
+			assert.Equal(t, "true", r.URL.Query().Get("with_accessory"))
+			if r.URL.Query().Get("page") == "1" {
+				w.Header().Add("Link", "</api/v2.0/projects/my-project/repositories/team%252Fapp/artifacts?page=2&page_size=5>; rel=\"next\"")
+			}
+			w.Write(artifactsResponseBytes)
+		case artifactsPath + "/v1.1":
+			w.Write([]byte(`{"digest": "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", "type": "IMAGE", "tags": [{"name": "v1.1"}]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	artifacts, nextPage, err := harbor.Artifacts(ctx, "my-project/team/app", common.MakePagination(5))
+	assert.Nil(t, err)
+	assert.Equal(t, &common.PaginationOption{Cursor: "2", Size: 5}, nextPage)
+	assert.Len(t, artifacts, 5)
+	signed := artifacts[1]
+	assert.Equal(t, []string{"v1.1", "latest"}, signed.TagNames())
+	assert.Equal(t, time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC), signed.PushTime.UTC())
+	assert.Equal(t, time.Date(2024, 2, 3, 8, 30, 0, 0, time.UTC), signed.PullTime.UTC())
+	assert.True(t, signed.IsSigned())
+	assert.True(t, signed.HasSBOM())
+	assert.True(t, signed.Tags[0].Immutable)
+	assert.Equal(t, "production", signed.Labels[0].Name)
+	assert.False(t, artifacts[0].IsSigned())
+	assert.True(t, artifacts[0].PullTime.IsZero())
+	assert.Equal(t, ArtifactTypeChart, artifacts[3].Type)
+	assert.Equal(t, "v8", artifacts[2].References[1].Platform.Variant)
+
+	_, nextPage, err = harbor.Artifacts(ctx, "my-project/team/app", *nextPage)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	_, _, err = harbor.Artifacts(ctx, "my-project/team/app", common.PaginationOption{Cursor: "x", Size: 5})
+	assert.Error(t, err)
+
+	artifact, err := harbor.Artifact(ctx, "my-project/team/app", "v1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", artifact.Digest)
+}
+
+func TestArtifactsReuseConnections(t *testing.T) {
+	connections := &atomic.Int32{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(artifactsResponseBytes)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "https://"))
+	assert.Nil(t, err)
+	harbor, err := NewHarborRegistry(&authn.AuthConfig{Username: "admin", Password: "Harbor12345"}, &registry, common.MakeRegistryOptions(false, false, true, "", "", "", common.Harbor))
+	assert.Nil(t, err)
+
+	//the requests share the client of the registry, including its transport skipping the TLS verification
+	for range 3 {
+		_, _, err := harbor.(*HarborRegistry).Artifacts(context.Background(), "my-project/team/app", common.MakePagination(5))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(1), connections.Load())
+}
+
+func TestGetLatestImagesByPushTime(t *testing.T) {
+	harbor, server := newTestHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != artifactsPath {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(artifactsResponseBytes)
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	//charts and untagged artifacts are skipped
+	tags, err := harbor.GetLatestTags("my-project/team/app", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"multi", "latest,v1.1", "v1.0"}, tags)
+
+	images, err := harbor.GetLatestImages(ctx, "my-project/team/app", 1, common.LatestTagsOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc", images[0].Digest)
+	assert.Equal(t, []common.PlatformImage{{Platform: images[0].Platforms[0].Platform, Digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111"}}, images[0].Platforms)
+
+	semver, err := common.SemverConstraintStrategy(">= 1.0")
+	assert.Nil(t, err)
+	images, err = harbor.GetLatestImages(ctx, "my-project/team/app", 3, common.LatestTagsOption{Strategy: semver})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"v1.1"}, images[0].Tags)
+
+	images, err = harbor.GetLatestImages(ctx, "my-project/team/app", 3, common.LatestTagsOption{Platform: common.PlatformOption{OS: "linux", Architecture: "arm64"}})
+	assert.Nil(t, err)
+	assert.Len(t, images, 1)
+	assert.Equal(t, "sha256:2222222222222222222222222222222222222222222222222222222222222222", images[0].Platforms[0].Digest)
+
+	images, err = harbor.GetLatestImages(ctx, "my-project/team/app", 3, common.LatestTagsOption{Filter: &common.TagFilter{Include: []string{"v*"}}})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"v1.1"}, images[0].Tags)
+}
+
+func TestGetLatestImagesByCreationTime(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v2 := registry.New()
+	digests := map[string]string{}
+	harbor, server := newTestHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != artifactsPath {
+			v2.ServeHTTP(w, r)
+			return
+		}
+		//the index was pushed last, harbor reports no creation time for indexes
+		w.Write([]byte(fmt.Sprintf(`[
+			{"digest": "%s", "type": "IMAGE", "push_time": "2024-02-01T00:00:00Z", "tags": [{"name": "multi"}], "references": [{"child_digest": "sha256:1111111111111111111111111111111111111111111111111111111111111111", "platform": {"architecture": "amd64", "os": "linux"}}]},
+			{"digest": "%s", "type": "IMAGE", "push_time": "2024-01-15T00:00:00Z", "tags": [{"name": "single"}], "extra_attrs": {"architecture": "amd64", "os": "linux", "created": "2024-01-01T00:00:00Z"}}
+		]`, digests["multi"], digests["single"])))
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	single, err := random.Image(256, 1)
+	assert.Nil(t, err)
+	single, err = mutate.CreatedAt(single, v1.Time{Time: base})
+	assert.Nil(t, err)
+	child, err := random.Image(256, 1)
+	assert.Nil(t, err)
+	child, err = mutate.CreatedAt(child, v1.Time{Time: base.Add(time.Hour)})
+	assert.Nil(t, err)
+	multi := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{Add: child, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}})
+	repo, err := name.NewRepository(harbor.Registry.Name()+"/my-project/team/app", name.Insecure)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag("single"), single))
+	assert.Nil(t, remote.WriteIndex(repo.Tag("multi"), multi))
+	for _, tag := range []string{"single", "multi"} {
+		desc, err := remote.Head(repo.Tag(tag))
+		assert.Nil(t, err)
+		digests[tag] = desc.Digest.String()
+	}
+
+	//the multi-platform image is ranked by the creation time of its platform image
+	images, err := harbor.GetLatestImages(ctx, "my-project/team/app", 2, common.LatestTagsOption{Strategy: common.CreatedStrategy()})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"multi"}, images[0].Tags)
+	assert.Equal(t, base.Add(time.Hour), images[0].Created.UTC())
+	assert.Equal(t, []string{"single"}, images[1].Tags)
+}
+
+func TestTaggedImageWithoutPlatform(t *testing.T) {
+	artifact := &Artifact{Digest: "sha256:ccc", Type: ArtifactTypeImage, Tags: []ArtifactTag{{Name: "multi"}}, References: []ArtifactReference{
+		{ChildDigest: "sha256:111"},
+		{ChildDigest: "sha256:222", Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+	}}
+	accepts := func(string) bool { return true }
+
+	image := taggedImage(artifact, common.AllPlatforms(), accepts)
+	assert.Len(t, image.Platforms, 2)
+	assert.Equal(t, common.PlatformImage{Digest: "sha256:111"}, image.Platforms[0])
+	image = taggedImage(artifact, common.PlatformOption{}, accepts)
+	assert.Equal(t, []common.PlatformImage{{Platform: v1.Platform{OS: "linux", Architecture: "amd64"}, Digest: "sha256:222"}}, image.Platforms)
+}
+
+func TestGetLatestImagesWithoutArtifactsAPI(t *testing.T) {
+	harbor, server := newTestHarbor(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case artifactsPath:
+			w.WriteHeader(http.StatusNotFound)
+		case "/v2/my-project/team/app/tags/list":
+			w.Write([]byte(`{"name": "my-project/team/app", "tags": ["v1", "latest"]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	tags, err := harbor.GetLatestTags("my-project/team/app", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"latest"}, tags)
+}
@@ -0,0 +1,151 @@
+package harbor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// AuthMethod is the kind of harbor credentials
+type AuthMethod string
+
+const (
+	AuthMethodBasic     AuthMethod = ""          // a harbor (database or LDAP) user with its password
+	AuthMethodRobot     AuthMethod = "robot"     // a robot account with its secret
+	AuthMethodOIDC      AuthMethod = "oidc"      // an OIDC user with the CLI secret of its harbor profile
+	AuthMethodAnonymous AuthMethod = "anonymous" // no credentials, only the public projects are accessible
+)
+
+// RobotAccountPrefix is the default prefix of the robot account names (robot$<name> or robot$<project>+<name>)
+const RobotAccountPrefix = "robot$"
+
+// IsRobotAccount reports whether username is a robot account name
+func IsRobotAccount(username string) bool {
+	return strings.HasPrefix(username, RobotAccountPrefix)
+}
+
+// MakeAuth returns the credentials of the auth method, the robot$ prefix is added to robot account names without a prefix.
+// It returns nil for anonymous access, or for basic auth without a username and a password
+func MakeAuth(method AuthMethod, username, secret string) (*authn.AuthConfig, error) {
+	switch method {
+	case AuthMethodAnonymous:
+		return nil, nil
+	case AuthMethodBasic:
+		if username == "" && secret == "" {
+			return nil, nil
+		}
+		return &authn.AuthConfig{Username: username, Password: secret}, nil
+	case AuthMethodRobot:
+		if username == "" || secret == "" {
+			return nil, fmt.Errorf("robot account requires a name and a secret")
+		}
+		//robot names with a custom prefix are kept as is
+		if !strings.Contains(username, "$") {
+			username = RobotAccountPrefix + username
+		}
+		return &authn.AuthConfig{Username: username, Password: secret}, nil
+	case AuthMethodOIDC:
+		if username == "" || secret == "" {
+			return nil, fmt.Errorf("OIDC authentication requires a username and a CLI secret")
+		}
+		return &authn.AuthConfig{Username: username, Password: secret}, nil
+	default:
+		return nil, fmt.Errorf("unsupported harbor auth method %s", method)
+	}
+}
+
+// addAuthHeader authenticates an API request with the basic credentials (user password, robot secret or OIDC CLI secret),
+// or with an OIDC ID token. No header is added for anonymous access
+func (h *HarborRegistry) addAuthHeader(req *http.Request) {
+	auth := h.GetAuth()
+	if common.ValidateAuth(auth) != nil {
+		return
+	}
+	switch {
+	case auth.Username != "" || auth.Password != "":
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password)))
+	case auth.Auth != "":
+		req.Header.Set("Authorization", "Basic "+auth.Auth)
+	case auth.IdentityToken != "":
+		req.Header.Set("Authorization", "Bearer "+auth.IdentityToken)
+	case auth.RegistryToken != "":
+		req.Header.Set("Authorization", "Bearer "+auth.RegistryToken)
+	}
+}
+
+// doV2 sends a request to a /v2 endpoint of repoName. When harbor answers with a bearer challenge
+// (anonymous access, or credentials only accepted by the token service) the request is sent again
+// with a token of the harbor token service, the tokens are cached by scope
+func (h *HarborRegistry) doV2(req *http.Request, repoName string) (*http.Response, error) {
+	scope := fmt.Sprintf("repository:%s:pull", repoName)
+	if token, ok := h.tokens.Load(scope); ok {
+		req.Header.Set("Authorization", "Bearer "+token.(string))
+	} else {
+		h.addAuthHeader(req)
+	}
+	client := h.HTTPClient
+	res, err := client.Do(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	challenge, ok := common.ParseBearerChallenge(res.Header.Get("WWW-Authenticate"))
+	if !ok {
+		return res, nil
+	}
+	res.Body.Close()
+	if challenge.Scope == "" {
+		challenge.Scope = scope
+	}
+	token, err := h.fetchToken(req.Context(), challenge)
+	if err != nil {
+		return nil, err
+	}
+	h.tokens.Store(scope, token)
+	retry := req.Clone(req.Context())
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return client.Do(retry)
+}
+
+// fetchToken requests a token of the challenge scope from the harbor token service,
+// anonymous tokens only grant pull access to public projects
+func (h *HarborRegistry) fetchToken(ctx context.Context, challenge common.BearerChallenge) (string, error) {
+	realm, err := url.Parse(challenge.Realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token service realm %s: %w", challenge.Realm, err)
+	}
+	query := realm.Query()
+	if challenge.Service != "" {
+		query.Set("service", challenge.Service)
+	}
+	query.Set("scope", challenge.Scope)
+	realm.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	h.addAuthHeader(req)
+	res, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if err := transport.CheckError(res, http.StatusOK); err != nil {
+		return "", err
+	}
+	token := &common.V2TokenResponse{}
+	if err := json.NewDecoder(res.Body).Decode(token); err != nil {
+		return "", err
+	}
+	if token.BearerToken() == "" {
+		return "", fmt.Errorf("token service %s returned no token", challenge.Realm)
+	}
+	return token.BearerToken(), nil
+}
@@ -0,0 +1,140 @@
+package harbor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeAuth(t *testing.T) {
+	auth, err := MakeAuth(AuthMethodRobot, "ci", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "robot$ci", Password: "secret"}, auth)
+	assert.True(t, IsRobotAccount(auth.Username))
+	auth, err = MakeAuth(AuthMethodRobot, "robot$my-project+ci", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "robot$my-project+ci", auth.Username)
+	//custom robot prefix
+	auth, err = MakeAuth(AuthMethodRobot, "bot$ci", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "bot$ci", auth.Username)
+	_, err = MakeAuth(AuthMethodRobot, "ci", "")
+	assert.Error(t, err)
+
+	auth, err = MakeAuth(AuthMethodOIDC, "alice", "cli-secret")
+	assert.Nil(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "alice", Password: "cli-secret"}, auth)
+	_, err = MakeAuth(AuthMethodOIDC, "", "cli-secret")
+	assert.Error(t, err)
+
+	auth, err = MakeAuth(AuthMethodAnonymous, "admin", "Harbor12345")
+	assert.Nil(t, err)
+	assert.Nil(t, auth)
+	auth, err = MakeAuth(AuthMethodBasic, "", "")
+	assert.Nil(t, err)
+	assert.Nil(t, auth)
+	_, err = MakeAuth("token", "admin", "Harbor12345")
+	assert.Error(t, err)
+}
+
+// tokenServiceHandler serves the tags of my-project/app only to the bearer token of the harbor token service
+func tokenServiceHandler(t *testing.T, expectedAuth string, tokenRequests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/service/token":
+			tokenRequests.Add(1)
+			assert.Equal(t, expectedAuth, r.Header.Get("Authorization"))
+			assert.Equal(t, "harbor-registry", r.URL.Query().Get("service"))
+			assert.Equal(t, "repository:my-project/app:pull", r.URL.Query().Get("scope"))
+			w.Write([]byte(`{"token": "registry-token", "expires_in": 1800}`))
+		case "/v2/my-project/app/tags/list":
+			if r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/service/token",service="harbor-registry",scope="repository:my-project/app:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"name": "my-project/app", "tags": ["v1", "v2"]}`))
+		case "/api/v2.0/repositories":
+			assert.Equal(t, expectedAuth, r.Header.Get("Authorization"))
+			w.Write([]byte(`[{"name": "my-project/app"}]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestBearerTokenFlow(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		auth         *authn.AuthConfig
+		expectedAuth string
+	}{
+		{name: "robot account", auth: &authn.AuthConfig{Username: "robot$ci", Password: "secret"}, expectedAuth: "Basic cm9ib3QkY2k6c2VjcmV0"},
+		{name: "anonymous", auth: nil, expectedAuth: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tokenRequests := &atomic.Int32{}
+			server := httptest.NewServer(tokenServiceHandler(t, test.expectedAuth, tokenRequests))
+			defer server.Close()
+			registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+			assert.Nil(t, err)
+			harbor, err := NewHarborRegistry(test.auth, &registry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Harbor))
+			assert.Nil(t, err)
+
+			repos, _, err := harbor.Catalog(context.Background(), common.NoPaginationOption(), common.CatalogOption{}, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"my-project/app"}, repos)
+			for range 2 {
+				tags, _, err := harbor.List("my-project/app", common.NoPaginationOption())
+				assert.Nil(t, err)
+				assert.Equal(t, []string{"v1", "v2"}, tags)
+			}
+			//the token is cached
+			assert.Equal(t, int32(1), tokenRequests.Load())
+		})
+	}
+}
+
+func TestAuthHeader(t *testing.T) {
+	registry, err := name.NewRegistry("harbor.example.com")
+	assert.Nil(t, err)
+	for _, test := range []struct {
+		auth     *authn.AuthConfig
+		expected string
+	}{
+		{auth: nil, expected: ""},
+		{auth: &authn.AuthConfig{}, expected: ""},
+		{auth: &authn.AuthConfig{Username: "admin", Password: "Harbor12345"}, expected: "Basic YWRtaW46SGFyYm9yMTIzNDU="},
+		{auth: &authn.AuthConfig{Auth: "YWRtaW46SGFyYm9yMTIzNDU="}, expected: "Basic YWRtaW46SGFyYm9yMTIzNDU="},
+		{auth: &authn.AuthConfig{IdentityToken: "id-token"}, expected: "Bearer id-token"},
+	} {
+		harbor, err := NewHarborRegistry(test.auth, &registry, common.MakeRegistryOptions(false, false, false, "", "", "", common.Harbor))
+		assert.Nil(t, err)
+		req, err := http.NewRequest(http.MethodGet, "https://harbor.example.com/api/v2.0/projects", nil)
+		assert.Nil(t, err)
+		harbor.(*HarborRegistry).addAuthHeader(req)
+		assert.Equal(t, test.expected, req.Header.Get("Authorization"))
+	}
+}
+
+func TestSkipTLSVerifyClient(t *testing.T) {
+	registry, err := name.NewRegistry("harbor.example.com")
+	assert.Nil(t, err)
+	harbor, err := NewHarborRegistry(nil, &registry, common.MakeRegistryOptions(false, false, true, "", "", "", common.Harbor))
+	assert.Nil(t, err)
+	client := harbor.(*HarborRegistry).HTTPClient
+	assert.True(t, client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+	//the default transport is left untouched
+	defaultTLSConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig
+	assert.True(t, defaultTLSConfig == nil || !defaultTLSConfig.InsecureSkipVerify, "default transport must verify certificates")
+	assert.NotSame(t, http.DefaultTransport, client.Transport)
+}
@@ -0,0 +1,71 @@
+package harbor
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// DeleteTag removes a tag from its artifact with the harbor tags API, the artifact and its other tags are kept
+func (h *HarborRegistry) DeleteTag(ctx context.Context, repoName string, tag string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error) {
+	if _, _, err := splitRepository(repoName); err != nil {
+		return nil, err
+	}
+	//resolve the artifact of the tag
+	result, err := h.DefaultRegistry.DeleteTag(ctx, repoName, tag, common.DeleteOption{DryRun: true}, options...)
+	if err != nil {
+		return nil, err
+	}
+	result.DryRun = opts.DryRun
+	if opts.DryRun {
+		return result, nil
+	}
+	uri, err := h.artifactsURL(repoName, result.Digest, "tags", tag)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.deleteRequest(ctx, uri); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteManifest deletes an artifact (and all its tags) with the harbor artifacts API
+func (h *HarborRegistry) DeleteManifest(ctx context.Context, repoName string, digest string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error) {
+	if _, _, err := splitRepository(repoName); err != nil {
+		return nil, err
+	}
+	result, err := h.DefaultRegistry.DeleteManifest(ctx, repoName, digest, common.DeleteOption{DryRun: true}, options...)
+	if err != nil {
+		return nil, err
+	}
+	result.DryRun = opts.DryRun
+	if opts.DryRun {
+		return result, nil
+	}
+	uri, err := h.artifactsURL(repoName, digest)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.deleteRequest(ctx, uri); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (h *HarborRegistry) deleteRequest(ctx context.Context, uri string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, uri, nil)
+	if err != nil {
+		return err
+	}
+	h.addAuthHeader(req)
+	res, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return transport.CheckError(res, http.StatusOK)
+}
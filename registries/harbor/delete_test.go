@@ -0,0 +1,61 @@
+package harbor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDigest = "sha256:4c5ff5f3be2ef1e4df37c2e7c3e9c9a3bd0b9f1b1d5d7c1a0e4d3b2a1f0e9d8c"
+
+func TestDelete(t *testing.T) {
+	deleted := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead && (r.URL.Path == "/v2/my-project/team/app/manifests/v1" || r.URL.Path == "/v2/my-project/team/app/manifests/"+testDigest):
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Content-Length", "100")
+			w.Header().Set("Docker-Content-Digest", testDigest)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.EscapedPath(), "/api/v2.0/"):
+			assert.Equal(t, "Basic YWRtaW46SGFyYm9yMTIzNDU=", r.Header.Get("Authorization"))
+			deleted = append(deleted, r.URL.EscapedPath())
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	harbor, err := NewHarborRegistry(&authn.AuthConfig{Username: "admin", Password: "Harbor12345"}, &registry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Harbor))
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	result, err := harbor.DeleteTag(ctx, "my-project/team/app", "v1", common.DeleteOption{DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, &common.DeleteResult{Repository: "my-project/team/app", Reference: "v1", Digest: testDigest, DryRun: true}, result)
+	assert.Empty(t, deleted)
+
+	result, err = harbor.DeleteTag(ctx, "my-project/team/app", "v1", common.DeleteOption{})
+	assert.Nil(t, err)
+	assert.False(t, result.DryRun)
+	result, err = harbor.DeleteManifest(ctx, "my-project/team/app", testDigest, common.DeleteOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, testDigest, result.Digest)
+	assert.Equal(t, []string{
+		"/api/v2.0/projects/my-project/repositories/team%252Fapp/artifacts/" + testDigest + "/tags/v1",
+		"/api/v2.0/projects/my-project/repositories/team%252Fapp/artifacts/" + testDigest,
+	}, deleted)
+
+	_, err = harbor.DeleteTag(ctx, "app", "v1", common.DeleteOption{})
+	assert.Error(t, err)
+}
@@ -2,8 +2,6 @@ package harbor
 
 import (
 	"context"
-	"crypto/tls"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -11,6 +9,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/armosec/registryx/common"
 	"github.com/armosec/registryx/interfaces"
@@ -25,13 +24,17 @@ func NewHarborRegistry(auth *authn.AuthConfig, registry *name.Registry, registry
 	if registry.Name() == "" {
 		return nil, fmt.Errorf("must provide a non empty registry")
 	}
-	reg := &HarborRegistry{DefaultRegistry: defaultregistry.DefaultRegistry{Registry: registry, Auth: auth, Cfg: registryCfg}}
+	//a single client reuses the connections of the API and token requests
+	client := &http.Client{Transport: registryCfg.Transport()}
+	reg := &HarborRegistry{DefaultRegistry: defaultregistry.DefaultRegistry{Registry: registry, Auth: auth, Cfg: registryCfg, HTTPClient: client}}
 	reg.This = reg
 	return reg, nil
 }
 
 type HarborRegistry struct {
 	defaultregistry.DefaultRegistry
+	// tokens are the bearer tokens of the /v2 endpoints by scope
+	tokens sync.Map
 }
 
 type RepositoryInfo struct {
@@ -44,7 +47,16 @@ func (*HarborRegistry) GetMaxPageSize() int {
 	return 100
 }
 
+// Catalog lists the repositories of the projects of options.Projects, of the configured project or all the visible repositories
 func (h *HarborRegistry) Catalog(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption, authenticator authn.Authenticator) ([]string, *common.PaginationOption, error) {
+	if len(options.Projects) != 0 {
+		return h.projectsCatalog(ctx, pagination, options)
+	}
+	return h.repositories(ctx, h.Cfg.Project(), pagination)
+}
+
+// repositories lists a page of the repositories of project, or of all the visible repositories if project is empty
+func (h *HarborRegistry) repositories(ctx context.Context, project string, pagination common.PaginationOption) ([]string, *common.PaginationOption, error) {
 	//if first pagination request set the page number (cursor) to 1
 	if len(pagination.Cursor) == 0 {
 		pagination.Cursor = "1"
@@ -56,12 +68,11 @@ func (h *HarborRegistry) Catalog(ctx context.Context, pagination common.Paginati
 		}
 	}
 	//create list repos request
-	req, err := h.repositoriesRequest(strconv.Itoa(pagination.Size), pagination.Cursor)
+	req, err := h.repositoriesRequest(ctx, project, strconv.Itoa(pagination.Size), pagination.Cursor)
 	if err != nil {
 		return nil, nil, err
 	}
-	//create client according to registry configuration
-	res, err := h.getClient().Do(req)
+	res, err := h.HTTPClient.Do(req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -85,15 +96,15 @@ func (h *HarborRegistry) Catalog(ctx context.Context, pagination common.Paginati
 	}
 }
 
-func (h *HarborRegistry) List(repoName string, pagination common.PaginationOption, options ...remote.Option) ([]string, *common.PaginationOption, error) {
+func (h *HarborRegistry) ListWithContext(ctx context.Context, repoName string, pagination common.PaginationOption, options ...remote.Option) ([]string, *common.PaginationOption, error) {
 	repo := RepositoryInfo{registryName: h.Registry.RegistryStr(), repositoryName: repoName}
 	//create list tag request
-	req, err := h.listTagsRequest(repo, strconv.Itoa(pagination.Size), pagination.Cursor)
+	req, err := h.listTagsRequest(ctx, repo, strconv.Itoa(pagination.Size), pagination.Cursor)
 	if err != nil {
 		return nil, nil, err
 	}
-	//create client according to registry configuration
-	res, err := h.getClient().Do(req)
+	//send with the registry credentials or with a token service bearer token
+	res, err := h.doV2(req, repoName)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -115,8 +126,8 @@ func (h *HarborRegistry) List(repoName string, pagination common.PaginationOptio
 	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
 		return nil, nil, err
 	}
-	//append tags names to tagList
-	tagList = append(tagList, parsed.Tags...)
+	//append the filtered tags names to tagList
+	tagList = append(tagList, h.Cfg.TagFilter().Apply(parsed.Tags)...)
 	//get next pagination option
 	nextPagination, err := common.GetNextV2Pagination(res)
 	return tagList, nextPagination, err
@@ -178,26 +189,14 @@ func getNextPageOption(res *http.Response) (*common.PaginationOption, error) {
 
 }
 
-func (h *HarborRegistry) getClient() *http.Client {
-	var client *http.Client
-	if h.Cfg.SkipTLSVerify() {
-		skipVerifyTransport := &(*http.DefaultTransport.(*http.Transport)) // make shallow copy
-		skipVerifyTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		client = &http.Client{Transport: skipVerifyTransport}
-	} else {
-		client = &http.Client{}
-	}
-	return client
-}
-
-func (h *HarborRegistry) repositoriesRequest(pageSize string, pageNum string) (*http.Request, error) {
+func (h *HarborRegistry) repositoriesRequest(ctx context.Context, project string, pageSize string, pageNum string) (*http.Request, error) {
 	uri := &url.URL{
 		Scheme: h.requestScheme(),
 		Host:   h.Registry.RegistryStr(),
 	}
 	//if project is specified then use the projects api to list repos in the project
-	if len(h.Cfg.Project()) != 0 {
-		uri.Path = fmt.Sprintf("/api/v2.0/projects/%s/repositories", h.Cfg.Project())
+	if len(project) != 0 {
+		uri.Path = fmt.Sprintf("/api/v2.0/projects/%s/repositories", project)
 	} else {
 		//no project - get all visible repositories
 		uri.Path = "/api/v2.0/repositories"
@@ -211,7 +210,7 @@ func (h *HarborRegistry) repositoriesRequest(pageSize string, pageNum string) (*
 		uri.RawQuery = paginationParams.Encode()
 	}
 
-	req, err := http.NewRequest("GET", uri.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -219,7 +218,7 @@ func (h *HarborRegistry) repositoriesRequest(pageSize string, pageNum string) (*
 	return req, nil
 }
 
-func (h *HarborRegistry) listTagsRequest(repo RepositoryInfo, size string, cursor string) (*http.Request, error) {
+func (h *HarborRegistry) listTagsRequest(ctx context.Context, repo RepositoryInfo, size string, cursor string) (*http.Request, error) {
 	uri := &url.URL{
 		Scheme: h.requestScheme(),
 		Host:   repo.registryName,
@@ -235,16 +234,32 @@ func (h *HarborRegistry) listTagsRequest(repo RepositoryInfo, size string, curso
 		uri.RawQuery = paginationParams.Encode()
 	}
 
-	req, err := http.NewRequest("GET", uri.String(), nil)
+	//authenticated by doV2
+	return http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
+}
+
+// artifactsURL returns the URL of the artifacts API of a repository ("<project>/<repository>") followed by the escaped path elements,
+// harbor requires the repository name to be URL encoded twice (e.g a/b is a%252Fb)
+func (h *HarborRegistry) artifactsURL(repoName string, elements ...string) (string, error) {
+	project, repository, err := splitRepository(repoName)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	h.addAuthHeader(req)
-	return req, nil
+	uri := fmt.Sprintf("%s://%s/api/v2.0/projects/%s/repositories/%s/artifacts", h.requestScheme(), h.Registry.RegistryStr(),
+		url.PathEscape(project), url.PathEscape(url.PathEscape(repository)))
+	for _, element := range elements {
+		uri += "/" + url.PathEscape(element)
+	}
+	return uri, nil
 }
 
-func (h *HarborRegistry) addAuthHeader(req *http.Request) {
-	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(h.Auth.Username+":"+h.Auth.Password)))
+// splitRepository splits a harbor repository name to its project and the repository name in the project
+func splitRepository(repoName string) (project string, repository string, err error) {
+	project, repository, found := strings.Cut(repoName, "/")
+	if !found || project == "" || repository == "" {
+		return "", "", fmt.Errorf("invalid harbor repository %s, expected <project>/<repository>", repoName)
+	}
+	return project, repository, nil
 }
 
 func (h *HarborRegistry) requestScheme() string {
@@ -192,13 +192,13 @@ func TestInsecureRequest(t *testing.T) {
 	assert.Nil(t, err)
 	harbor := iHarbor.(*HarborRegistry)
 	//test repo request scheme
-	req, err := harbor.repositoriesRequest("0", "")
+	req, err := harbor.repositoriesRequest(context.Background(), "", "0", "")
 	assert.Nil(t, err)
 	assert.Equal(t, "https", req.URL.Scheme)
 	//test list tags request scheme
 	repoData := RepositoryInfo{repositoryName: "repo"}
 	repoData.registryName = registry.RegistryStr()
-	req, err = harbor.listTagsRequest(repoData, "0", "")
+	req, err = harbor.listTagsRequest(context.Background(), repoData, "0", "")
 	assert.Nil(t, err)
 	assert.Equal(t, "https", req.URL.Scheme)
 
@@ -212,13 +212,13 @@ func TestInsecureRequest(t *testing.T) {
 	assert.Nil(t, err)
 	harbor = iHarbor.(*HarborRegistry)
 	//test repo request scheme
-	req, err = harbor.repositoriesRequest("0", "")
+	req, err = harbor.repositoriesRequest(context.Background(), "", "0", "")
 	assert.Nil(t, err)
 	assert.Equal(t, "http", req.URL.Scheme)
 	//test list tags request scheme
 	repoData = RepositoryInfo{repositoryName: "repo"}
 	repoData.registryName = registry.RegistryStr()
-	req, err = harbor.listTagsRequest(repoData, "0", "")
+	req, err = harbor.listTagsRequest(context.Background(), repoData, "0", "")
 	assert.Nil(t, err)
 	assert.Equal(t, "http", req.URL.Scheme)
 
@@ -235,3 +235,28 @@ func startTestClientServer(requestUrl string, handler http.Handler) (*httptest.S
 	testServer.StartTLS()
 	return testServer, nil
 }
+
+func TestListWithCanceledContext(t *testing.T) {
+	registry, err := name.NewRegistry("localhost:9111")
+	if err != nil {
+		t.Errorf("err1: %v", err.Error())
+	}
+	testServer, err := startTestClientServer("127.0.0.1:9111", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.String())
+	}))
+	if err != nil {
+		t.Error(err)
+	}
+	defer testServer.Close()
+
+	regOptions := common.MakeRegistryOptions(true, false, true, "", "", "", common.Harbor)
+	harbor, err := NewHarborRegistry(&authn.AuthConfig{Username: "admin", Password: "Harbor12345"}, &registry, regOptions)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = harbor.ListWithContext(ctx, "my-project/ca-ws", common.NoPaginationOption())
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = harbor.GetLatestTagsWithContext(ctx, "my-project/ca-ws", 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
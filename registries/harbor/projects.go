@@ -0,0 +1,231 @@
+package harbor
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/armosec/registryx/common"
+)
+
+// Project is a harbor project with its metadata
+type Project struct {
+	ID           int64           `json:"project_id"`
+	Name         string          `json:"name"`
+	OwnerName    string          `json:"owner_name"`
+	RepoCount    int64           `json:"repo_count"`
+	RegistryID   int64           `json:"registry_id"` // the upstream registry of a proxy cache project, 0 otherwise
+	CreationTime time.Time       `json:"creation_time"`
+	UpdateTime   time.Time       `json:"update_time"`
+	Metadata     ProjectMetadata `json:"metadata"`
+	Quota        *ProjectQuota   `json:"-"` // only set when the projects are listed with their quotas
+}
+
+// ProjectMetadata are the project settings, harbor returns the booleans as "true" or "false" strings
+type ProjectMetadata struct {
+	Public             string `json:"public"`
+	AutoScan           string `json:"auto_scan"`
+	PreventVul         string `json:"prevent_vul"`
+	Severity           string `json:"severity"`
+	EnableContentTrust string `json:"enable_content_trust"`
+	RetentionID        string `json:"retention_id"`
+}
+
+// ProjectQuota are the hard limits and the usage of the project resources, -1 is unlimited
+type ProjectQuota struct {
+	Hard map[string]int64 `json:"hard"`
+	Used map[string]int64 `json:"used"`
+}
+
+// StorageLimit returns the storage limit of the project in bytes, -1 is unlimited
+func (q *ProjectQuota) StorageLimit() int64 {
+	if limit, ok := q.Hard["storage"]; ok {
+		return limit
+	}
+	return -1
+}
+
+// StorageUsed returns the storage used by the project in bytes
+func (q *ProjectQuota) StorageUsed() int64 {
+	return q.Used["storage"]
+}
+
+func (p *Project) IsPublic() bool {
+	return p.Metadata.Public == "true"
+}
+
+// AutoScan reports whether the images are scanned on push
+func (p *Project) AutoScan() bool {
+	return p.Metadata.AutoScan == "true"
+}
+
+// IsProxyCache reports whether the project is a pull through cache of another registry
+func (p *Project) IsProxyCache() bool {
+	return p.RegistryID != 0
+}
+
+// Projects lists a page of the projects visible to the user, the cursor is the page number.
+// With withQuota the quota of each project is requested as well (one request per project)
+func (h *HarborRegistry) Projects(ctx context.Context, pagination common.PaginationOption, withQuota bool) ([]Project, *common.PaginationOption, error) {
+	if pagination.Cursor == "" {
+		pagination.Cursor = "1"
+	} else if _, err := strconv.Atoi(pagination.Cursor); err != nil {
+		return nil, nil, fmt.Errorf("invalid pagination, cursor must be an integer")
+	}
+	query := url.Values{}
+	query.Add("with_detail", "true")
+	if pagination.Size > 0 {
+		query.Add("page_size", strconv.Itoa(pagination.Size))
+		query.Add("page", pagination.Cursor)
+	}
+	projects := []Project{}
+	res, err := h.getJSON(ctx, h.apiURL("projects")+"?"+query.Encode(), &projects)
+	if err != nil {
+		return nil, nil, err
+	}
+	if withQuota {
+		for i := range projects {
+			if projects[i].Quota, err = h.projectQuota(ctx, projects[i].ID); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if pagination.Size == 0 {
+		return projects, nil, nil
+	}
+	nextPagination, err := getNextPageOption(res)
+	return projects, nextPagination, err
+}
+
+func (h *HarborRegistry) projectQuota(ctx context.Context, projectID int64) (*ProjectQuota, error) {
+	query := url.Values{}
+	query.Add("reference", "project")
+	query.Add("reference_id", strconv.FormatInt(projectID, 10))
+	quotas := []ProjectQuota{}
+	if _, err := h.getJSON(ctx, h.apiURL("quotas")+"?"+query.Encode(), &quotas); err != nil {
+		return nil, err
+	}
+	if len(quotas) == 0 {
+		return nil, nil
+	}
+	return &quotas[0], nil
+}
+
+// allProjects returns all the projects visible to the user
+func (h *HarborRegistry) allProjects(ctx context.Context) ([]Project, error) {
+	projects := []Project{}
+	for page, nextPage, err := h.Projects(ctx, common.MakePagination(h.GetMaxPageSize()), false); ; page, nextPage, err = h.Projects(ctx, *nextPage, false) {
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, page...)
+		if nextPage == nil {
+			return projects, nil
+		}
+	}
+}
+
+// catalogProjects returns the sorted names of the projects selected by the catalog options,
+// the proxy cache projects matched by a pattern are skipped unless options.IncludeProxyCache is set
+func (h *HarborRegistry) catalogProjects(ctx context.Context, options common.CatalogOption) ([]string, error) {
+	for _, pattern := range options.Projects {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid project pattern %q: %w", pattern, err)
+		}
+	}
+	projects, err := h.allProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, project := range projects {
+		if options.IsPublic && !project.IsPublic() {
+			continue
+		}
+		if slices.Contains(options.Projects, project.Name) {
+			names = append(names, project.Name)
+			continue
+		}
+		if project.IsProxyCache() && !options.IncludeProxyCache {
+			continue
+		}
+		for _, pattern := range options.Projects {
+			if matched, _ := path.Match(pattern, project.Name); matched {
+				names = append(names, project.Name)
+				break
+			}
+		}
+	}
+	//projects named explicitly must exist
+	for _, pattern := range options.Projects {
+		if !isProjectPattern(pattern) && !slices.ContainsFunc(projects, func(project Project) bool { return project.Name == pattern }) {
+			return nil, fmt.Errorf("project %s not found", pattern)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func isProjectPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// projectsCatalog lists the repositories of several projects, the cursor is "<project>:<page>".
+// Each page holds the repositories of a single project so a page can be shorter than the page size
+func (h *HarborRegistry) projectsCatalog(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption) ([]string, *common.PaginationOption, error) {
+	projects, err := h.catalogProjects(ctx, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pagination.Size == 0 {
+		repos := []string{}
+		for _, project := range projects {
+			for page, nextPage, err := h.repositories(ctx, project, common.MakePagination(h.GetMaxPageSize())); ; page, nextPage, err = h.repositories(ctx, project, *nextPage) {
+				if err != nil {
+					return nil, nil, err
+				}
+				repos = append(repos, page...)
+				if nextPage == nil {
+					break
+				}
+			}
+		}
+		return repos, nil, nil
+	}
+	if len(projects) == 0 {
+		return []string{}, nil, nil
+	}
+	project, page := projects[0], "1"
+	if pagination.Cursor != "" {
+		var found bool
+		if project, page, found = strings.Cut(pagination.Cursor, ":"); !found {
+			return nil, nil, fmt.Errorf("invalid pagination, cursor must be <project>:<page>")
+		}
+	}
+	current := slices.Index(projects, project)
+	if current == -1 {
+		return nil, nil, fmt.Errorf("invalid pagination, project %s is not cataloged", project)
+	}
+	repos, nextPage, err := h.repositories(ctx, project, common.PaginationOption{Cursor: page, Size: pagination.Size})
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case nextPage != nil:
+		nextPage.Cursor = project + ":" + nextPage.Cursor
+	case current+1 < len(projects):
+		nextPage = &common.PaginationOption{Cursor: projects[current+1] + ":1", Size: pagination.Size}
+	}
+	return repos, nextPage, nil
+}
+
+// apiURL returns the URL of a harbor API path
+func (h *HarborRegistry) apiURL(apiPath string) string {
+	return fmt.Sprintf("%s://%s/api/v2.0/%s", h.requestScheme(), h.Registry.RegistryStr(), apiPath)
+}
@@ -0,0 +1,137 @@
+package harbor
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed fixtures/projectsResponse.json
+var projectsResponseBytes []byte
+
+// projectRepos are the repositories of the test projects
+var projectRepos = map[string][]string{
+	"library":         {"library/nginx", "library/redis"},
+	"team-a":          {"team-a/api", "team-a/web", "team-a/worker"},
+	"team-b":          {"team-b/db"},
+	"dockerhub-cache": {"dockerhub-cache/library/alpine"},
+	"team-cache":      {"team-cache/grafana/grafana"},
+}
+
+func projectsHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Basic YWRtaW46SGFyYm9yMTIzNDU=", r.Header.Get("Authorization"))
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/api/v2.0/projects":
+			assert.Equal(t, "true", query.Get("with_detail"))
+			w.Write(projectsResponseBytes)
+		case r.URL.Path == "/api/v2.0/quotas":
+			assert.Equal(t, "project", query.Get("reference"))
+			if query.Get("reference_id") == "1" {
+				w.Write([]byte(`[{"id": 1, "ref": {"id": 1, "name": "library"}, "hard": {"storage": 10737418240}, "used": {"storage": 1024}}]`))
+			} else {
+				w.Write([]byte(`[]`))
+			}
+		case strings.HasPrefix(r.URL.Path, "/api/v2.0/projects/") && strings.HasSuffix(r.URL.Path, "/repositories"):
+			repos := projectRepos[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v2.0/projects/"), "/repositories")]
+			if query.Get("page_size") != "" {
+				size, _ := strconv.Atoi(query.Get("page_size"))
+				page, _ := strconv.Atoi(query.Get("page"))
+				start := min((page-1)*size, len(repos))
+				if len(repos) > start+size {
+					w.Header().Add("Link", fmt.Sprintf("<%s?page=%d&page_size=%d>; rel=\"next\"", r.URL.Path, page+1, size))
+				}
+				repos = repos[start:min(start+size, len(repos))]
+			}
+			names := []string{}
+			for _, repo := range repos {
+				names = append(names, fmt.Sprintf(`{"name": %q}`, repo))
+			}
+			w.Write([]byte("[" + strings.Join(names, ",") + "]"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestProjects(t *testing.T) {
+	harbor, server := newTestHarbor(t, projectsHandler(t))
+	defer server.Close()
+
+	projects, nextPage, err := harbor.Projects(context.Background(), common.NoPaginationOption(), true)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Len(t, projects, 5)
+	library := projects[0]
+	assert.True(t, library.IsPublic())
+	assert.True(t, library.AutoScan())
+	assert.False(t, library.IsProxyCache())
+	assert.Equal(t, int64(10737418240), library.Quota.StorageLimit())
+	assert.Equal(t, int64(1024), library.Quota.StorageUsed())
+	assert.False(t, projects[1].IsPublic())
+	assert.Equal(t, "4", projects[1].Metadata.RetentionID)
+	assert.Nil(t, projects[1].Quota)
+	assert.True(t, projects[2].IsProxyCache())
+	assert.Equal(t, int64(2), projects[2].RegistryID)
+}
+
+func TestProjectsCatalog(t *testing.T) {
+	harbor, server := newTestHarbor(t, projectsHandler(t))
+	defer server.Close()
+	ctx := context.Background()
+
+	//proxy cache projects matched by a pattern are skipped
+	repos, nextPage, err := harbor.Catalog(ctx, common.NoPaginationOption(), common.CatalogOption{Projects: []string{"team-*"}}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Equal(t, []string{"team-a/api", "team-a/web", "team-a/worker", "team-b/db"}, repos)
+	repos, _, err = harbor.Catalog(ctx, common.NoPaginationOption(), common.CatalogOption{Projects: []string{"team-*"}, IncludeProxyCache: true}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-a/api", "team-a/web", "team-a/worker", "team-b/db", "team-cache/grafana/grafana"}, repos)
+	//named proxy cache projects are included
+	repos, _, err = harbor.Catalog(ctx, common.NoPaginationOption(), common.CatalogOption{Projects: []string{"library", "dockerhub-cache"}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dockerhub-cache/library/alpine", "library/nginx", "library/redis"}, repos)
+	repos, _, err = harbor.Catalog(ctx, common.NoPaginationOption(), common.CatalogOption{Projects: []string{"*"}, IsPublic: true}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"library/nginx", "library/redis"}, repos)
+
+	_, _, err = harbor.Catalog(ctx, common.NoPaginationOption(), common.CatalogOption{Projects: []string{"missing"}}, nil)
+	assert.Error(t, err)
+	_, _, err = harbor.Catalog(ctx, common.NoPaginationOption(), common.CatalogOption{Projects: []string{"team-["}}, nil)
+	assert.Error(t, err)
+}
+
+func TestProjectsCatalogPagination(t *testing.T) {
+	harbor, server := newTestHarbor(t, projectsHandler(t))
+	defer server.Close()
+	ctx := context.Background()
+	options := common.CatalogOption{Projects: []string{"team-b", "team-a"}}
+
+	repos, nextPage, err := harbor.Catalog(ctx, common.MakePagination(2), options, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-a/api", "team-a/web"}, repos)
+	assert.Equal(t, &common.PaginationOption{Cursor: "team-a:2", Size: 2}, nextPage)
+	repos, nextPage, err = harbor.Catalog(ctx, *nextPage, options, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-a/worker"}, repos)
+	assert.Equal(t, &common.PaginationOption{Cursor: "team-b:1", Size: 2}, nextPage)
+	repos, nextPage, err = harbor.Catalog(ctx, *nextPage, options, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-b/db"}, repos)
+	assert.Nil(t, nextPage)
+
+	_, _, err = harbor.Catalog(ctx, common.PaginationOption{Cursor: "2", Size: 2}, options, nil)
+	assert.Error(t, err)
+	_, _, err = harbor.Catalog(ctx, common.PaginationOption{Cursor: "library:1", Size: 2}, options, nil)
+	assert.Error(t, err)
+}
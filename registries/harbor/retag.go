@@ -0,0 +1,48 @@
+package harbor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// Retag adds newTag to the artifact of reference with the harbor tags API,
+// harbor rejects manifest PUTs for tags protected by immutability rules so the V2 API is not used
+func (h *HarborRegistry) Retag(ctx context.Context, repoName string, reference string, newTag string, options ...remote.Option) (string, error) {
+	if _, _, err := splitRepository(repoName); err != nil {
+		return "", err
+	}
+	digest, err := h.DefaultRegistry.Resolve(ctx, repoName, reference, options...)
+	if err != nil {
+		return "", err
+	}
+	uri, err := h.artifactsURL(repoName, digest, "tags")
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(struct {
+		Name string `json:"name"`
+	}{Name: newTag})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	h.addAuthHeader(req)
+	res, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if err := transport.CheckError(res, http.StatusCreated, http.StatusOK); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
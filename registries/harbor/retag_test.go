@@ -0,0 +1,53 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetag(t *testing.T) {
+	tagged := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead && r.URL.Path == "/v2/my-project/team/app/manifests/sha-abc":
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Content-Length", "100")
+			w.Header().Set("Docker-Content-Digest", testDigest)
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/api/v2.0/projects/my-project/repositories/team%252Fapp/artifacts/"+testDigest+"/tags":
+			assert.Equal(t, "Basic YWRtaW46SGFyYm9yMTIzNDU=", r.Header.Get("Authorization"))
+			body := struct {
+				Name string `json:"name"`
+			}{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			tagged = append(tagged, body.Name)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	harbor, err := NewHarborRegistry(&authn.AuthConfig{Username: "admin", Password: "Harbor12345"}, &registry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Harbor))
+	assert.Nil(t, err)
+
+	digest, err := harbor.Retag(context.Background(), "my-project/team/app", "sha-abc", "1.4.2")
+	assert.Nil(t, err)
+	assert.Equal(t, testDigest, digest)
+	assert.Equal(t, []string{"1.4.2"}, tagged)
+
+	_, err = harbor.Retag(context.Background(), "app", "sha-abc", "1.4.2")
+	assert.Error(t, err)
+}
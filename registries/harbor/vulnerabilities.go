@@ -0,0 +1,238 @@
+package harbor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// the vulnerability report mime types of the harbor scanner adapters, in order of preference
+const (
+	VulnerabilityReportMimeType       = "application/vnd.security.vulnerability.report; version=1.1"
+	NativeVulnerabilityReportMimeType = "application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0"
+)
+
+var vulnerabilityReportMimeTypes = []string{VulnerabilityReportMimeType, NativeVulnerabilityReportMimeType}
+
+// harbor scan statuses
+const (
+	scanStatusPending   = "Pending"
+	scanStatusScheduled = "Scheduled"
+	scanStatusRunning   = "Running"
+	scanStatusSuccess   = "Success"
+	scanStatusError     = "Error"
+	scanStatusStopped   = "Stopped"
+)
+
+// DefaultScanPollInterval is the interval between the scan status requests of WaitForScan
+const DefaultScanPollInterval = 5 * time.Second
+
+// ScanOverview is the status and the summary of the last scan of an artifact
+type ScanOverview struct {
+	ReportID        string                `json:"report_id"`
+	ScanStatus      string                `json:"scan_status"`
+	Severity        string                `json:"severity"`
+	Duration        int64                 `json:"duration"`
+	StartTime       time.Time             `json:"start_time"`
+	EndTime         time.Time             `json:"end_time"`
+	CompletePercent int                   `json:"complete_percent"`
+	Scanner         *Scanner              `json:"scanner"`
+	Summary         *VulnerabilitySummary `json:"summary"`
+}
+
+type Scanner struct {
+	Name    string `json:"name"`
+	Vendor  string `json:"vendor"`
+	Version string `json:"version"`
+}
+
+func (s *Scanner) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(s.Name + " " + s.Version)
+}
+
+type VulnerabilitySummary struct {
+	Total   int            `json:"total"`
+	Fixable int            `json:"fixable"`
+	Summary map[string]int `json:"summary"` // the number of vulnerabilities by severity
+}
+
+// Status converts the harbor scan status to a provider neutral one
+func (o *ScanOverview) Status() common.ScanStatus {
+	if o == nil {
+		return common.ScanStatusNotScanned
+	}
+	switch o.ScanStatus {
+	case scanStatusPending, scanStatusScheduled:
+		return common.ScanStatusQueued
+	case scanStatusRunning:
+		return common.ScanStatusRunning
+	case scanStatusSuccess:
+		return common.ScanStatusScanned
+	case scanStatusError, scanStatusStopped:
+		return common.ScanStatusFailed
+	default:
+		return common.ScanStatusNotScanned
+	}
+}
+
+// Scan returns the overview of the last scan of the artifact, nil if it was never scanned
+// or if the artifact was not requested with its scan overview
+func (a *Artifact) Scan() *ScanOverview {
+	for _, mimeType := range vulnerabilityReportMimeTypes {
+		if overview, ok := a.ScanOverview[mimeType]; ok {
+			return &overview
+		}
+	}
+	return nil
+}
+
+// harborVulnerabilityReport is a report of the additions/vulnerabilities API
+type harborVulnerabilityReport struct {
+	GeneratedAt     time.Time `json:"generated_at"`
+	Scanner         *Scanner  `json:"scanner"`
+	Severity        string    `json:"severity"`
+	Vulnerabilities []struct {
+		ID            string   `json:"id"`
+		Package       string   `json:"package"`
+		Version       string   `json:"version"`
+		FixVersion    string   `json:"fix_version"`
+		Severity      string   `json:"severity"`
+		Description   string   `json:"description"`
+		Links         []string `json:"links"`
+		PreferredCVSS *struct {
+			ScoreV3 *float64 `json:"score_v3"`
+			ScoreV2 *float64 `json:"score_v2"`
+		} `json:"preferred_cvss"`
+	} `json:"vulnerabilities"`
+}
+
+// VulnerabilityReport returns the report of the last harbor scan of a tag or a digest of repoName.
+// Artifacts that were not scanned yet (or are being scanned) have a report without vulnerabilities and the scan status,
+// artifacts that are not images (charts, CNAB) are unsupported
+func (h *HarborRegistry) VulnerabilityReport(ctx context.Context, repoName string, reference string) (*common.VulnerabilityReport, error) {
+	artifact, err := h.Artifact(ctx, repoName, reference)
+	if err != nil {
+		return nil, err
+	}
+	report := &common.VulnerabilityReport{Digest: artifact.Digest, Vulnerabilities: []common.Vulnerability{}}
+	if artifact.Type != "" && artifact.Type != ArtifactTypeImage {
+		report.Status = common.ScanStatusUnsupported
+		return report, nil
+	}
+	overview := artifact.Scan()
+	report.Status = overview.Status()
+	if report.Status != common.ScanStatusScanned {
+		return report, nil
+	}
+	report.Scanner = overview.Scanner.String()
+	uri, err := h.artifactsURL(repoName, artifact.Digest, "additions", "vulnerabilities")
+	if err != nil {
+		return nil, err
+	}
+	reports := map[string]harborVulnerabilityReport{}
+	if _, err := h.getJSON(ctx, uri, &reports); err != nil {
+		return nil, err
+	}
+	for _, mimeType := range vulnerabilityReportMimeTypes {
+		if harborReport, ok := reports[mimeType]; ok {
+			normalizeReport(report, &harborReport)
+			break
+		}
+	}
+	return report, nil
+}
+
+func normalizeReport(report *common.VulnerabilityReport, harborReport *harborVulnerabilityReport) {
+	report.GeneratedAt = harborReport.GeneratedAt
+	if harborReport.Scanner != nil {
+		report.Scanner = harborReport.Scanner.String()
+	}
+	for _, vulnerability := range harborReport.Vulnerabilities {
+		normalized := common.Vulnerability{
+			ID:          vulnerability.ID,
+			Package:     vulnerability.Package,
+			Version:     vulnerability.Version,
+			FixVersion:  vulnerability.FixVersion,
+			Severity:    common.ParseSeverity(vulnerability.Severity),
+			Description: vulnerability.Description,
+			Links:       vulnerability.Links,
+		}
+		if cvss := vulnerability.PreferredCVSS; cvss != nil {
+			if cvss.ScoreV3 != nil {
+				normalized.CVSSScore = *cvss.ScoreV3
+			} else if cvss.ScoreV2 != nil {
+				normalized.CVSSScore = *cvss.ScoreV2
+			}
+		}
+		report.Vulnerabilities = append(report.Vulnerabilities, normalized)
+	}
+}
+
+// ScanArtifact asks harbor to scan a tag or a digest of repoName, the scan runs asynchronously (see WaitForScan)
+func (h *HarborRegistry) ScanArtifact(ctx context.Context, repoName string, reference string) error {
+	uri, err := h.artifactsURL(repoName, reference, "scan")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, nil)
+	if err != nil {
+		return err
+	}
+	h.addAuthHeader(req)
+	res, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return transport.CheckError(res, http.StatusAccepted)
+}
+
+// WaitForScan polls the scan status of a tag or a digest of repoName every pollInterval (DefaultScanPollInterval if not positive)
+// until the scan is over or ctx is done, and returns the vulnerability report of the scan.
+// A not scanned artifact is polled as a queued one since a triggered scan may not be registered yet
+func (h *HarborRegistry) WaitForScan(ctx context.Context, repoName string, reference string, pollInterval time.Duration) (*common.VulnerabilityReport, error) {
+	if pollInterval <= 0 {
+		pollInterval = DefaultScanPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		artifact, err := h.Artifact(ctx, repoName, reference)
+		if err != nil {
+			return nil, err
+		}
+		if artifact.Scan().Status().Done() || artifact.Type != "" && artifact.Type != ArtifactTypeImage {
+			return h.VulnerabilityReport(ctx, repoName, artifact.Digest)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan scans a tag or a digest of repoName and waits for its vulnerability report, unless the last report is fresher than maxAge
+func (h *HarborRegistry) Scan(ctx context.Context, repoName string, reference string, maxAge time.Duration, pollInterval time.Duration) (*common.VulnerabilityReport, error) {
+	report, err := h.VulnerabilityReport(ctx, repoName, reference)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case report.IsFresh(maxAge), report.Status == common.ScanStatusUnsupported:
+		return report, nil
+	case report.Status == common.ScanStatusQueued, report.Status == common.ScanStatusRunning:
+		return h.WaitForScan(ctx, repoName, report.Digest, pollInterval)
+	}
+	if err := h.ScanArtifact(ctx, repoName, report.Digest); err != nil {
+		return nil, err
+	}
+	return h.WaitForScan(ctx, repoName, report.Digest, pollInterval)
+}
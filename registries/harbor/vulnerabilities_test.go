@@ -0,0 +1,138 @@
+package harbor
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed fixtures/vulnerabilitiesResponse.json
+var vulnerabilitiesResponseBytes []byte
+
+const scannedDigest = "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+
+// scanningHarbor serves an artifact whose scan goes through the given harbor scan statuses, one per artifact request
+type scanningHarbor struct {
+	lock     sync.Mutex
+	statuses []string
+	scans    int
+}
+
+func (s *scanningHarbor) artifact(artifactType string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	overview := ""
+	if len(s.statuses) > 0 {
+		overview = fmt.Sprintf(`, "scan_overview": {%q: {"report_id": "r1", "scan_status": %q, "severity": "Critical", "scanner": {"name": "Trivy", "version": "v0.47.0"}, "summary": {"total": 3, "fixable": 2, "summary": {"Critical": 1, "High": 1, "Unknown": 1}}}}`,
+			VulnerabilityReportMimeType, s.statuses[0])
+		if len(s.statuses) > 1 {
+			s.statuses = s.statuses[1:]
+		}
+	}
+	return fmt.Sprintf(`{"digest": %q, "type": %q, "tags": [{"name": "v1.1"}]%s}`, scannedDigest, artifactType, overview)
+}
+
+func (s *scanningHarbor) handler(t *testing.T, artifactType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			assert.Contains(t, r.Header.Get("X-Accept-Vulnerabilities"), VulnerabilityReportMimeType)
+		}
+		switch {
+		case r.Method == http.MethodGet && (r.URL.EscapedPath() == artifactsPath+"/v1.1" || r.URL.EscapedPath() == artifactsPath+"/"+scannedDigest):
+			assert.Equal(t, "true", r.URL.Query().Get("with_scan_overview"))
+			w.Write([]byte(s.artifact(artifactType)))
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == artifactsPath+"/"+scannedDigest+"/additions/vulnerabilities":
+			w.Write(vulnerabilitiesResponseBytes)
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == artifactsPath+"/"+scannedDigest+"/scan":
+			s.lock.Lock()
+			s.scans++
+			s.statuses = []string{"Pending", "Running", "Success"}
+			s.lock.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestVulnerabilityReport(t *testing.T) {
+	scanning := &scanningHarbor{statuses: []string{"Success"}}
+	harbor, server := newTestHarbor(t, scanning.handler(t, ArtifactTypeImage))
+	defer server.Close()
+
+	report, err := harbor.VulnerabilityReport(context.Background(), "my-project/team/app", "v1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, scannedDigest, report.Digest)
+	assert.Equal(t, common.ScanStatusScanned, report.Status)
+	assert.Equal(t, "Trivy v0.47.0", report.Scanner)
+	assert.Equal(t, time.Date(2024, 2, 1, 10, 5, 0, 0, time.UTC), report.GeneratedAt.UTC())
+	assert.Len(t, report.Vulnerabilities, 3)
+	assert.Equal(t, common.Vulnerability{
+		ID:          "CVE-2023-0286",
+		Package:     "libssl3",
+		Version:     "3.0.7-r0",
+		FixVersion:  "3.0.8-r0",
+		Severity:    common.SeverityHigh,
+		CVSSScore:   7.4,
+		Description: "There is a type confusion vulnerability relating to X.400 address processing.",
+		Links:       []string{"https://avd.aquasec.com/nvd/cve-2023-0286"},
+	}, report.Vulnerabilities[0])
+	assert.Equal(t, 9.8, report.Vulnerabilities[1].CVSSScore)
+	assert.Equal(t, map[common.Severity]int{common.SeverityCritical: 1, common.SeverityHigh: 1, common.SeverityUnknown: 1}, report.Summary())
+	assert.Len(t, report.Fixable(), 2)
+	//the fixture report is old
+	assert.False(t, report.IsFresh(time.Hour))
+
+	//not scanned artifacts and charts have no vulnerabilities
+	scanning.statuses = nil
+	report, err = harbor.VulnerabilityReport(context.Background(), "my-project/team/app", "v1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, common.ScanStatusNotScanned, report.Status)
+	assert.Empty(t, report.Vulnerabilities)
+
+	chartHarbor, chartServer := newTestHarbor(t, (&scanningHarbor{}).handler(t, ArtifactTypeChart))
+	defer chartServer.Close()
+	report, err = chartHarbor.VulnerabilityReport(context.Background(), "my-project/team/app", "v1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, common.ScanStatusUnsupported, report.Status)
+}
+
+func TestScan(t *testing.T) {
+	scanning := &scanningHarbor{statuses: []string{"Success"}}
+	harbor, server := newTestHarbor(t, scanning.handler(t, ArtifactTypeImage))
+	defer server.Close()
+	ctx := context.Background()
+
+	//the last report is older than a day
+	report, err := harbor.Scan(ctx, "my-project/team/app", "v1.1", 24*time.Hour, time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, scanning.scans)
+	assert.Equal(t, common.ScanStatusScanned, report.Status)
+	assert.Len(t, report.Vulnerabilities, 3)
+
+	//the last report is fresh
+	report, err = harbor.Scan(ctx, "my-project/team/app", "v1.1", 100*365*24*time.Hour, time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, scanning.scans)
+	assert.Len(t, report.Vulnerabilities, 3)
+
+	//a scan still running when the context is done
+	scanning.statuses = []string{"Running"}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = harbor.WaitForScan(timeoutCtx, "my-project/team/app", "v1.1", time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	scanning.statuses = []string{"Error"}
+	report, err = harbor.WaitForScan(ctx, "my-project/team/app", "v1.1", time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, common.ScanStatusFailed, report.Status)
+}
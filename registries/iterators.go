@@ -0,0 +1,56 @@
+package registries
+
+import (
+	"context"
+	"iter"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Repositories iterates over all the repositories in the registry catalog.
+// The catalog is fetched page by page (each provider defines its own cursor) so only a single page is kept in memory,
+// iteration stops on the first error or when the caller breaks out of the loop
+func Repositories(ctx context.Context, registry interfaces.IRegistry, options common.CatalogOption) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		fetch := func(pagination common.PaginationOption) ([]string, *common.PaginationOption, error) {
+			return registry.Catalog(ctx, pagination, options, nil)
+		}
+		iteratePages(ctx, common.MakePagination(registry.GetMaxPageSize()), fetch, yield)
+	}
+}
+
+// Tags iterates over all the tags of a repository, page by page
+func Tags(ctx context.Context, registry interfaces.IRegistry, repoName string, options ...remote.Option) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		fetch := func(pagination common.PaginationOption) ([]string, *common.PaginationOption, error) {
+			return registry.ListWithContext(ctx, repoName, pagination, options...)
+		}
+		iteratePages(ctx, common.MakePagination(registry.GetMaxPageSize()), fetch, yield)
+	}
+}
+
+func iteratePages(ctx context.Context, pagination common.PaginationOption, fetch func(common.PaginationOption) ([]string, *common.PaginationOption, error), yield func(string, error) bool) {
+	for {
+		if err := ctx.Err(); err != nil {
+			yield("", err)
+			return
+		}
+		page, nextPage, err := fetch(pagination)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		for _, item := range page {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		//an empty page does not mean the end, only a missing (or a non advancing) next page does
+		if nextPage == nil || *nextPage == pagination {
+			return
+		}
+		pagination = *nextPage
+	}
+}
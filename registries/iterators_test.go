@@ -0,0 +1,96 @@
+package registries
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedRegistry serves items in pages of pageSize, page numbers are used as cursors
+type pagedRegistry struct {
+	interfaces.IRegistry
+	pages    [][]string
+	requests int
+}
+
+func (r *pagedRegistry) GetMaxPageSize() int {
+	return 2
+}
+
+func (r *pagedRegistry) page(pagination common.PaginationOption) ([]string, *common.PaginationOption, error) {
+	r.requests++
+	pageNum := 0
+	if pagination.Cursor != "" {
+		var err error
+		if pageNum, err = strconv.Atoi(pagination.Cursor); err != nil {
+			return nil, nil, err
+		}
+	}
+	if pageNum >= len(r.pages) {
+		return nil, nil, fmt.Errorf("page %d out of range", pageNum)
+	}
+	if pageNum == len(r.pages)-1 {
+		return r.pages[pageNum], nil, nil
+	}
+	return r.pages[pageNum], &common.PaginationOption{Cursor: strconv.Itoa(pageNum + 1), Size: pagination.Size}, nil
+}
+
+func (r *pagedRegistry) Catalog(_ context.Context, pagination common.PaginationOption, _ common.CatalogOption, _ authn.Authenticator) ([]string, *common.PaginationOption, error) {
+	return r.page(pagination)
+}
+
+func (r *pagedRegistry) ListWithContext(_ context.Context, _ string, pagination common.PaginationOption, _ ...remote.Option) ([]string, *common.PaginationOption, error) {
+	return r.page(pagination)
+}
+
+func TestRepositoriesIterator(t *testing.T) {
+	//an empty page in the middle must not stop the iteration
+	reg := &pagedRegistry{pages: [][]string{{"a", "b"}, {}, {"c"}}}
+	repos := []string{}
+	for repo, err := range Repositories(context.Background(), reg, common.CatalogOption{}) {
+		assert.Nil(t, err)
+		repos = append(repos, repo)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, repos)
+	assert.Equal(t, 3, reg.requests)
+}
+
+func TestTagsIteratorBreak(t *testing.T) {
+	reg := &pagedRegistry{pages: [][]string{{"v1", "v2"}, {"v3", "v4"}, {"v5"}}}
+	tags := []string{}
+	for tag, err := range Tags(context.Background(), reg, "repo") {
+		assert.Nil(t, err)
+		tags = append(tags, tag)
+		if tag == "v3" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"v1", "v2", "v3"}, tags)
+	//no page is fetched after the caller stopped
+	assert.Equal(t, 2, reg.requests)
+}
+
+func TestIteratorErrors(t *testing.T) {
+	reg := &pagedRegistry{pages: [][]string{}}
+	count := 0
+	for _, err := range Tags(context.Background(), reg, "repo") {
+		assert.Error(t, err)
+		count++
+	}
+	assert.Equal(t, 1, count)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reg = &pagedRegistry{pages: [][]string{{"a"}}}
+	for _, err := range Repositories(ctx, reg, common.CatalogOption{}) {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+	assert.Equal(t, 0, reg.requests)
+}
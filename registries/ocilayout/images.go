@@ -0,0 +1,247 @@
+package ocilayout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/armosec/registryx/common"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// GetLatestTags returns the latest tags of repoName in descending order by the image creation time,
+// multiple tags on a single image are sent as a comma separated string
+func (reg *LayoutRegistry) GetLatestTags(repoName string, depth int, options ...remote.Option) ([]string, error) {
+	return reg.GetLatestTagsWithContext(context.Background(), repoName, depth, options...)
+}
+
+func (reg *LayoutRegistry) GetLatestTagsWithContext(ctx context.Context, repoName string, depth int, options ...remote.Option) ([]string, error) {
+	images, err := reg.GetLatestImages(ctx, repoName, depth, common.LatestTagsOption{}, options...)
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, image := range images {
+		tags = append(tags, strings.Join(image.Tags, ","))
+	}
+	return tags, nil
+}
+
+// GetLatestImages returns the latest images of repoName in descending order by the image creation time (or by opts.Strategy),
+// layouts do not record push times so the push time strategy ranks by creation time
+func (reg *LayoutRegistry) GetLatestImages(ctx context.Context, repoName string, depth int, opts common.LatestTagsOption, options ...remote.Option) ([]common.TaggedImage, error) {
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = common.CreatedStrategy()
+	}
+	allTags, _, err := reg.ListWithContext(ctx, repoName, common.NoPaginationOption())
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, tag := range opts.Filter.Apply(allTags) {
+		if strategy.Accepts(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if strategy.RanksTags() {
+		sort.SliceStable(tags, func(i, j int) bool {
+			return strategy.NewerTag(tags[i], tags[j])
+		})
+	}
+	latestImages := []*common.TaggedImage{}
+	for _, tag := range tags {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		//the newest tags come first, stop once depth images are found
+		if strategy.RanksTags() && len(latestImages) >= depth {
+			break
+		}
+		desc, err := reg.lookup(repoName, tag, opts.Platform)
+		if err != nil {
+			return nil, err
+		}
+		image, err := reg.describeImage(*desc, opts.Platform)
+		if err != nil {
+			return nil, err
+		}
+		//no image for the selected platform
+		if image == nil {
+			continue
+		}
+		if existingImage := getByDigest(latestImages, image.Digest); existingImage != nil {
+			existingImage.Tags = append(existingImage.Tags, tag)
+			continue
+		}
+		image.Tags = []string{tag}
+		latestImages = append(latestImages, image)
+	}
+	if !strategy.RanksTags() {
+		sort.SliceStable(latestImages, func(i, j int) bool {
+			return strategy.NewerImage(latestImages[i], latestImages[j])
+		})
+	}
+	images := []common.TaggedImage{}
+	for _, image := range latestImages[:min(depth, len(latestImages))] {
+		if !strategy.RanksTags() {
+			common.SortImageTags(image.Tags)
+		}
+		images = append(images, *image)
+	}
+	return images, nil
+}
+
+func getByDigest(images []*common.TaggedImage, digest string) *common.TaggedImage {
+	for _, image := range images {
+		if image.Digest == digest {
+			return image
+		}
+	}
+	return nil
+}
+
+// describeImage returns the digest and creation time of an image,
+// for an index it returns the index digest with the images matching platform (nil if none)
+func (reg *LayoutRegistry) describeImage(desc v1.Descriptor, platform common.PlatformOption) (*common.TaggedImage, error) {
+	root, err := reg.Path.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case desc.MediaType.IsIndex():
+		index, err := root.ImageIndex(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		platforms, err := common.PlatformImages(index, platform)
+		if err != nil || len(platforms) == 0 {
+			return nil, err
+		}
+		image := &common.TaggedImage{Digest: desc.Digest.String(), Platforms: platforms}
+		for _, platformImage := range platforms {
+			if platformImage.Created.After(image.Created) {
+				image.Created = platformImage.Created
+			}
+		}
+		return image, nil
+	case desc.MediaType.IsImage():
+		image, err := root.Image(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		cf, err := image.ConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		if !platform.IsDefault() && !platform.Matches(cf.Platform()) {
+			return nil, nil
+		}
+		return &common.TaggedImage{Digest: desc.Digest.String(), Created: cf.Created.Time}, nil
+	default:
+		return nil, fmt.Errorf("unsupported MediaType: %s", desc.MediaType)
+	}
+}
+
+// Inspect returns the manifest, configuration and layers of a tag or a digest in repoName,
+// for an index the images matching platform are listed along with the first one's manifest and configuration
+func (reg *LayoutRegistry) Inspect(ctx context.Context, repoName string, reference string, platform common.PlatformOption, options ...remote.Option) (*common.ImageInspection, error) {
+	ref, err := common.MakeReference(repoName, reference, reg.Registry)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := reg.lookup(repoName, reference, platform)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := reg.Path.Bytes(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	root, err := reg.Path.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	inspection := &common.ImageInspection{
+		Reference:   ref.String(),
+		MediaType:   desc.MediaType,
+		Digest:      desc.Digest.String(),
+		Size:        int64(len(raw)),
+		RawManifest: raw,
+	}
+	switch {
+	case desc.MediaType.IsIndex():
+		index, err := root.ImageIndex(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if inspection.Index, err = index.IndexManifest(); err != nil {
+			return nil, err
+		}
+		if inspection.Platforms, err = common.PlatformImages(index, platform); err != nil {
+			return nil, err
+		}
+		if platform.All || len(inspection.Platforms) == 0 {
+			break
+		}
+		hash, err := v1.NewHash(inspection.Platforms[0].Digest)
+		if err != nil {
+			return nil, err
+		}
+		image, err := index.Image(hash)
+		if err != nil {
+			return nil, err
+		}
+		if err := common.InspectImage(image, inspection); err != nil {
+			return nil, err
+		}
+	case desc.MediaType.IsImage():
+		image, err := root.Image(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if err := common.InspectImage(image, inspection); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported manifest media type %s", desc.MediaType)
+	}
+	return inspection, nil
+}
+
+// Resolve returns the manifest digest of a tag, a digest is returned as is if the layout has its manifest
+func (reg *LayoutRegistry) Resolve(ctx context.Context, repoName string, reference string, options ...remote.Option) (string, error) {
+	desc, err := reg.lookup(repoName, reference, common.PlatformOption{})
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
+// manifestMediaType returns the media type of a manifest, untyped OCI manifests are typed by their fields
+func manifestMediaType(raw []byte) (types.MediaType, error) {
+	manifest := struct {
+		SchemaVersion int               `json:"schemaVersion"`
+		MediaType     types.MediaType   `json:"mediaType"`
+		Config        json.RawMessage   `json:"config"`
+		Manifests     []json.RawMessage `json:"manifests"`
+	}{}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return "", err
+	}
+	switch {
+	case manifest.MediaType != "":
+		return manifest.MediaType, nil
+	case manifest.SchemaVersion == 1:
+		return types.DockerManifestSchema1, nil
+	case manifest.Manifests != nil:
+		return types.OCIImageIndex, nil
+	case manifest.Config != nil:
+		return types.OCIManifestSchema1, nil
+	}
+	return "", fmt.Errorf("unknown manifest type")
+}
@@ -0,0 +1,252 @@
+package ocilayout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// LAYOUT_REGISTRY is the registry name of the references of a layout
+const LAYOUT_REGISTRY = "oci-layout.local"
+
+// LayoutRegistry is an IRegistry over a local OCI image layout directory.
+// The repositories and tags are read from the "<repository>:<tag>" ref.name annotations of index.json,
+// plain tag annotations belong to the repository of their containerd image name or to the directory name
+type LayoutRegistry struct {
+	Path        layout.Path
+	Registry    *name.Registry
+	Cfg         *common.RegistryOptions
+	MaxPageSize *int
+	// defaultRepository is the repository of the plain tag annotations
+	defaultRepository string
+	// lock serializes the index.json updates
+	lock sync.Mutex
+}
+
+// entry is a tagged manifest of index.json
+type entry struct {
+	repository string
+	tag        string
+	descriptor v1.Descriptor
+}
+
+func NewLayoutRegistry(dir string, registryCfg *common.RegistryOptions) (interfaces.IRegistry, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("must provide a non empty layout directory")
+	}
+	path, err := layout.FromPath(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OCI layout %s: %w", dir, err)
+	}
+	registry, err := name.NewRegistry(LAYOUT_REGISTRY, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &LayoutRegistry{
+		Path:              path,
+		Registry:          &registry,
+		Cfg:               registryCfg,
+		defaultRepository: strings.ToLower(filepath.Base(absDir)),
+	}, nil
+}
+
+func (reg *LayoutRegistry) SetMaxPageSize(maxPageSize int) {
+	reg.MaxPageSize = &maxPageSize
+}
+
+func (reg *LayoutRegistry) GetMaxPageSize() int {
+	if reg.MaxPageSize != nil {
+		return *reg.MaxPageSize
+	}
+	return 1000
+}
+
+// GetAuth returns nil, a layout has no credentials
+func (reg *LayoutRegistry) GetAuth() *authn.AuthConfig {
+	return nil
+}
+
+func (reg *LayoutRegistry) GetRegistry() *name.Registry {
+	return reg.Registry
+}
+
+// Catalog lists the repositories of the layout in alphabetical order, the cursor is the last repository of the previous page
+func (reg *LayoutRegistry) Catalog(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption, authenticator authn.Authenticator) ([]string, *common.PaginationOption, error) {
+	entries, err := reg.entries()
+	if err != nil {
+		return nil, nil, err
+	}
+	repositories := []string{}
+	for _, entry := range entries {
+		if options.Namespaces != "" && !strings.HasPrefix(entry.repository, options.Namespaces+"/") {
+			continue
+		}
+		if !slices.Contains(repositories, entry.repository) {
+			repositories = append(repositories, entry.repository)
+		}
+	}
+	sort.Strings(repositories)
+	page, nextPage := paginate(repositories, pagination)
+	return page, nextPage, nil
+}
+
+func (reg *LayoutRegistry) List(repoName string, pagination common.PaginationOption, options ...remote.Option) ([]string, *common.PaginationOption, error) {
+	return reg.ListWithContext(context.Background(), repoName, pagination, options...)
+}
+
+// ListWithContext lists the tags of repoName in alphabetical order, the cursor is the last tag of the previous page
+func (reg *LayoutRegistry) ListWithContext(ctx context.Context, repoName string, pagination common.PaginationOption, options ...remote.Option) ([]string, *common.PaginationOption, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	entries, err := reg.repositoryEntries(repoName)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags := []string{}
+	for _, entry := range entries {
+		if !slices.Contains(tags, entry.tag) {
+			tags = append(tags, entry.tag)
+		}
+	}
+	sort.Strings(tags)
+	page, nextPage := paginate(tags, pagination)
+	//the next page is computed from the unfiltered page
+	return reg.Cfg.TagFilter().Apply(page), nextPage, nil
+}
+
+// paginate returns the page of the sorted values after the cursor
+func paginate(values []string, pagination common.PaginationOption) ([]string, *common.PaginationOption) {
+	if pagination.Cursor != "" {
+		start, _ := slices.BinarySearch(values, pagination.Cursor)
+		if start < len(values) && values[start] == pagination.Cursor {
+			start++
+		}
+		values = values[start:]
+	}
+	if pagination.Size <= 0 || len(values) <= pagination.Size {
+		return values, nil
+	}
+	page := values[:pagination.Size]
+	return page, &common.PaginationOption{Cursor: page[len(page)-1], Size: pagination.Size}
+}
+
+// entries returns the tagged manifests of index.json
+func (reg *LayoutRegistry) entries() ([]entry, error) {
+	index, err := reg.Path.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	entries := []entry{}
+	for _, descriptor := range indexManifest.Manifests {
+		if entry, ok := reg.entryOf(descriptor); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// entryOf returns the repository and tag of an index.json manifest, ok is false for untagged manifests
+func (reg *LayoutRegistry) entryOf(descriptor v1.Descriptor) (tagged entry, ok bool) {
+	refName, ok := descriptor.Annotations[common.RefNameAnnotation]
+	if !ok {
+		return entry{}, false
+	}
+	repository, tag := common.ParseLayoutRefName(refName)
+	if repository == "" {
+		repository = reg.defaultRepository
+		if imageName, ok := descriptor.Annotations[common.ImageNameAnnotation]; ok {
+			if ref, err := name.ParseReference(imageName, name.WeakValidation); err == nil {
+				repository = ref.Context().RepositoryStr()
+			}
+		}
+	}
+	return entry{repository: repository, tag: tag, descriptor: descriptor}, true
+}
+
+func (reg *LayoutRegistry) repositoryEntries(repoName string) ([]entry, error) {
+	entries, err := reg.entries()
+	if err != nil {
+		return nil, err
+	}
+	repositoryEntries := []entry{}
+	for _, entry := range entries {
+		if entry.repository == repoName {
+			repositoryEntries = append(repositoryEntries, entry)
+		}
+	}
+	if len(repositoryEntries) == 0 {
+		return nil, fmt.Errorf("repository %s not found in layout %s", repoName, reg.Path)
+	}
+	return repositoryEntries, nil
+}
+
+// lookup returns the manifest descriptor of a tag or a digest of repoName, a tag with a manifest per platform
+// (as exported for selected platforms) resolves to the one matching platform, or to the first one if none does
+func (reg *LayoutRegistry) lookup(repoName string, reference string, platform common.PlatformOption) (*v1.Descriptor, error) {
+	if strings.Contains(reference, ":") {
+		hash, err := v1.NewHash(reference)
+		if err != nil {
+			return nil, err
+		}
+		return reg.describeBlob(hash)
+	}
+	entries, err := reg.repositoryEntries(repoName)
+	if err != nil {
+		return nil, err
+	}
+	var found *v1.Descriptor
+	for _, entry := range entries {
+		if entry.tag != reference {
+			continue
+		}
+		if entry.descriptor.Platform == nil || platform.Matches(entry.descriptor.Platform) {
+			return &entry.descriptor, nil
+		}
+		if found == nil {
+			found = &entry.descriptor
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("tag %s not found in repository %s", reference, repoName)
+	}
+	return found, nil
+}
+
+// describeBlob returns the descriptor of a manifest blob of the layout
+func (reg *LayoutRegistry) describeBlob(hash v1.Hash) (*v1.Descriptor, error) {
+	raw, err := reg.Path.Bytes(hash)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("manifest %s not found in layout %s", hash.String(), reg.Path)
+		}
+		return nil, err
+	}
+	mediaType, err := manifestMediaType(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.Descriptor{MediaType: mediaType, Size: int64(len(raw)), Digest: hash}, nil
+}
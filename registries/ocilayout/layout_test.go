@@ -0,0 +1,216 @@
+package ocilayout
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func randomImage(t *testing.T, created time.Time, architecture string) v1.Image {
+	image, err := random.Image(64, 1)
+	assert.Nil(t, err)
+	config, err := image.ConfigFile()
+	assert.Nil(t, err)
+	config.Created = v1.Time{Time: created}
+	config.OS, config.Architecture = "linux", architecture
+	image, err = mutate.ConfigFile(image, config)
+	assert.Nil(t, err)
+	return image
+}
+
+func appendImage(t *testing.T, path layout.Path, image v1.Image, refName string) v1.Hash {
+	assert.Nil(t, path.AppendImage(image, layout.WithAnnotations(map[string]string{common.RefNameAnnotation: refName})))
+	digest, err := image.Digest()
+	assert.Nil(t, err)
+	return digest
+}
+
+// newLayout writes a layout with the team/app and team/db repositories and a plain tag of the directory repository
+func newLayout(t *testing.T) (*LayoutRegistry, map[string]v1.Hash) {
+	dir := filepath.Join(t.TempDir(), "Bundle")
+	path, err := layout.Write(dir, empty.Index)
+	assert.Nil(t, err)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	digests := map[string]v1.Hash{}
+	old := randomImage(t, base, "amd64")
+	digests["1.0.0"] = appendImage(t, path, old, "team/app:1.0.0")
+	appendImage(t, path, old, "team/app:stable")
+	digests["1.2.0"] = appendImage(t, path, randomImage(t, base.Add(time.Hour), "amd64"), "team/app:1.2.0")
+	digests["pr-7"] = appendImage(t, path, randomImage(t, base.Add(2*time.Hour), "amd64"), "team/app:pr-7")
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: randomImage(t, base, "amd64"), Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: randomImage(t, base.Add(3*time.Hour), "arm64"), Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}}},
+	)
+	assert.Nil(t, path.AppendIndex(index, layout.WithAnnotations(map[string]string{common.RefNameAnnotation: "team/db:multi"})))
+	digests["multi"], err = index.Digest()
+	assert.Nil(t, err)
+	digests["plain"] = appendImage(t, path, randomImage(t, base, "amd64"), "plain")
+
+	iRegistry, err := NewLayoutRegistry(dir, common.MakeRegistryOptions(false, false, false, "", "", "", common.OCILayout))
+	assert.Nil(t, err)
+	return iRegistry.(*LayoutRegistry), digests
+}
+
+func TestCatalogAndList(t *testing.T) {
+	reg, _ := newLayout(t)
+	ctx := context.Background()
+
+	repositories, nextPage, err := reg.Catalog(ctx, common.NoPaginationOption(), common.CatalogOption{}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Equal(t, []string{"bundle", "team/app", "team/db"}, repositories)
+	repositories, nextPage, err = reg.Catalog(ctx, common.MakePagination(2), common.CatalogOption{}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bundle", "team/app"}, repositories)
+	assert.Equal(t, &common.PaginationOption{Cursor: "team/app", Size: 2}, nextPage)
+	repositories, nextPage, err = reg.Catalog(ctx, *nextPage, common.CatalogOption{}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Equal(t, []string{"team/db"}, repositories)
+	repositories, _, err = reg.Catalog(ctx, common.NoPaginationOption(), common.CatalogOption{Namespaces: "team"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team/app", "team/db"}, repositories)
+
+	tags, nextPage, err := reg.List("team/app", common.MakePagination(3))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.2.0", "pr-7"}, tags)
+	assert.NotNil(t, nextPage)
+	tags, nextPage, err = reg.List("team/app", *nextPage)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Equal(t, []string{"stable"}, tags)
+	_, _, err = reg.List("missing", common.NoPaginationOption())
+	assert.Error(t, err)
+
+	reg.Cfg.WithTagFilter(&common.TagFilter{Exclude: []string{"pr-*"}})
+	tags, _, err = reg.List("team/app", common.NoPaginationOption())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.2.0", "stable"}, tags)
+}
+
+func TestGetLatestImages(t *testing.T) {
+	reg, digests := newLayout(t)
+	ctx := context.Background()
+
+	tags, err := reg.GetLatestTags("team/app", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pr-7", "1.2.0", "1.0.0,stable"}, tags)
+
+	images, err := reg.GetLatestImages(ctx, "team/app", 1, common.LatestTagsOption{Strategy: common.SemverStrategy()})
+	assert.Nil(t, err)
+	assert.Len(t, images, 1)
+	assert.Equal(t, []string{"1.2.0"}, images[0].Tags)
+	assert.Equal(t, digests["1.2.0"].String(), images[0].Digest)
+
+	images, err = reg.GetLatestImages(ctx, "team/app", 2, common.LatestTagsOption{Filter: &common.TagFilter{Exclude: []string{"pr-*"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.2.0"}, images[0].Tags)
+
+	images, err = reg.GetLatestImages(ctx, "team/db", 1, common.LatestTagsOption{Platform: common.PlatformOption{OS: "linux", Architecture: "arm64"}})
+	assert.Nil(t, err)
+	assert.Equal(t, digests["multi"].String(), images[0].Digest)
+	assert.Equal(t, "arm64", images[0].Platforms[0].Platform.Architecture)
+	assert.Equal(t, time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC), images[0].Created.UTC())
+
+	tags, err = reg.GetLatestTags("bundle", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"plain"}, tags)
+}
+
+func TestInspectAndResolve(t *testing.T) {
+	reg, digests := newLayout(t)
+	ctx := context.Background()
+
+	inspection, err := reg.Inspect(ctx, "team/app", "1.2.0", common.PlatformOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, digests["1.2.0"].String(), inspection.Digest)
+	assert.Equal(t, "linux", inspection.Config.OS)
+	assert.Len(t, inspection.Layers, 1)
+	assert.Equal(t, LAYOUT_REGISTRY+"/team/app:1.2.0", inspection.Reference)
+
+	inspection, err = reg.Inspect(ctx, "team/db", digests["multi"].String(), common.AllPlatforms())
+	assert.Nil(t, err)
+	assert.True(t, inspection.IsIndex())
+	assert.Len(t, inspection.Platforms, 2)
+	assert.Equal(t, types.OCIImageIndex, inspection.MediaType)
+
+	digest, err := reg.Resolve(ctx, "team/app", "stable")
+	assert.Nil(t, err)
+	assert.Equal(t, digests["1.0.0"].String(), digest)
+	digest, err = reg.Resolve(ctx, "team/app", digests["1.2.0"].String())
+	assert.Nil(t, err)
+	assert.Equal(t, digests["1.2.0"].String(), digest)
+	_, err = reg.Resolve(ctx, "team/app", "missing")
+	assert.Error(t, err)
+	_, err = reg.Resolve(ctx, "team/app", "sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	assert.Error(t, err)
+}
+
+func TestRetagAndDelete(t *testing.T) {
+	reg, digests := newLayout(t)
+	ctx := context.Background()
+
+	digest, err := reg.Retag(ctx, "team/app", "1.2.0", "stable")
+	assert.Nil(t, err)
+	assert.Equal(t, digests["1.2.0"].String(), digest)
+	digest, err = reg.Resolve(ctx, "team/app", "stable")
+	assert.Nil(t, err)
+	assert.Equal(t, digests["1.2.0"].String(), digest)
+	_, err = reg.Retag(ctx, "team/app", digests["pr-7"].String(), "candidate")
+	assert.Nil(t, err)
+	tags, _, err := reg.List("team/app", common.NoPaginationOption())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.2.0", "candidate", "pr-7", "stable"}, tags)
+
+	result, err := reg.DeleteTag(ctx, "team/app", "pr-7", common.DeleteOption{DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, &common.DeleteResult{Repository: "team/app", Reference: "pr-7", Digest: digests["pr-7"].String(), DryRun: true}, result)
+	_, err = reg.DeleteTag(ctx, "team/app", "pr-7", common.DeleteOption{})
+	assert.Nil(t, err)
+	_, err = reg.Resolve(ctx, "team/app", "pr-7")
+	assert.Error(t, err)
+
+	//deletes both 1.2.0 and stable
+	_, err = reg.DeleteManifest(ctx, "team/app", digests["1.2.0"].String(), common.DeleteOption{})
+	assert.Nil(t, err)
+	tags, _, err = reg.List("team/app", common.NoPaginationOption())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0", "candidate"}, tags)
+
+	_, err = reg.DeleteTag(ctx, "team/app", "missing", common.DeleteOption{})
+	assert.Error(t, err)
+	_, err = reg.DeleteManifest(ctx, "team/app", "1.0.0", common.DeleteOption{})
+	assert.Error(t, err)
+}
+
+func TestReferrers(t *testing.T) {
+	reg, digests := newLayout(t)
+	subject := digests["1.2.0"]
+	sbom := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), common.SPDXMediaType)
+	sbom = mutate.Subject(sbom, v1.Descriptor{MediaType: types.DockerManifestSchema2, Digest: subject, Size: 100}).(v1.Image)
+	assert.Nil(t, reg.Path.AppendImage(sbom))
+	signature, err := random.Image(16, 1)
+	assert.Nil(t, err)
+	appendImage(t, reg.Path, signature, "team/app:"+common.CosignTag(subject.String(), common.CosignSignatureTagSuffix))
+
+	referrers, err := reg.Referrers(context.Background(), "team/app", subject.String())
+	assert.Nil(t, err)
+	assert.Len(t, referrers, 2)
+	assert.True(t, referrers[0].IsSBOM())
+	assert.True(t, referrers[1].IsSignature())
+	assert.Equal(t, common.CosignTag(subject.String(), common.CosignSignatureTagSuffix), referrers[1].Tag)
+
+	referrers, err = reg.Referrers(context.Background(), "team/app", digests["1.0.0"].String())
+	assert.Nil(t, err)
+	assert.Empty(t, referrers)
+}
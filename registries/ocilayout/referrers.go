@@ -0,0 +1,81 @@
+package ocilayout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/armosec/registryx/common"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Referrers lists the artifacts of index.json with digest as their subject
+// and the cosign tag based artifacts (sha256-<hex>.sig, .att and .sbom) of repoName
+func (reg *LayoutRegistry) Referrers(ctx context.Context, repoName string, digest string, options ...remote.Option) ([]common.Referrer, error) {
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return nil, fmt.Errorf("referrers require a digest, got %s", digest)
+	}
+	index, err := reg.Path.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	referrers := []common.Referrer{}
+	seen := map[v1.Hash]bool{}
+	for _, desc := range indexManifest.Manifests {
+		if seen[desc.Digest] || !desc.MediaType.IsImage() {
+			continue
+		}
+		raw, err := reg.Path.Bytes(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		manifest := subjectManifest{}
+		if err := json.Unmarshal(raw, &manifest); err != nil {
+			return nil, err
+		}
+		if manifest.Subject == nil || manifest.Subject.Digest != hash {
+			continue
+		}
+		seen[desc.Digest] = true
+		artifactType := manifest.ArtifactType
+		if artifactType == "" {
+			artifactType = string(manifest.Config.MediaType)
+		}
+		referrers = append(referrers, common.Referrer{
+			Digest:       desc.Digest.String(),
+			MediaType:    desc.MediaType,
+			ArtifactType: artifactType,
+			Size:         desc.Size,
+			Annotations:  manifest.Annotations,
+		})
+	}
+	for _, suffix := range common.CosignTagSuffixes {
+		tag := common.CosignTag(digest, suffix)
+		for _, entry := range reg.tagEntries(repoName, tag) {
+			raw, err := reg.Path.Bytes(entry.descriptor.Digest)
+			if err != nil {
+				return nil, err
+			}
+			referrer, err := common.CosignReferrer(entry.descriptor, raw, tag, suffix)
+			if err != nil {
+				return nil, err
+			}
+			referrers = append(referrers, *referrer)
+		}
+	}
+	return referrers, nil
+}
+
+// subjectManifest is the part of an OCI 1.1 manifest needed to find and type a referrer
+type subjectManifest struct {
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Config       v1.Descriptor     `json:"config"`
+	Subject      *v1.Descriptor    `json:"subject,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
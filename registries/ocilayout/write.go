@@ -0,0 +1,130 @@
+package ocilayout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/armosec/registryx/common"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Retag adds newTag to index.json for the manifest of a tag or a digest, a tag with a manifest per platform
+// is retagged with all its manifests. The previous manifests of newTag are untagged
+func (reg *LayoutRegistry) Retag(ctx context.Context, repoName string, reference string, newTag string, options ...remote.Option) (string, error) {
+	if newTag == "" || strings.Contains(newTag, ":") {
+		return "", fmt.Errorf("invalid tag %s", newTag)
+	}
+	descriptors := []v1.Descriptor{}
+	if strings.Contains(reference, ":") {
+		desc, err := reg.lookup(repoName, reference, common.PlatformOption{})
+		if err != nil {
+			return "", err
+		}
+		descriptors = append(descriptors, *desc)
+	} else {
+		for _, entry := range reg.tagEntries(repoName, reference) {
+			descriptors = append(descriptors, entry.descriptor)
+		}
+		if len(descriptors) == 0 {
+			return "", fmt.Errorf("tag %s not found in repository %s", reference, repoName)
+		}
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	if err := reg.Path.RemoveDescriptors(reg.tagMatcher(repoName, newTag)); err != nil {
+		return "", err
+	}
+	for _, desc := range descriptors {
+		tagged := v1.Descriptor{
+			MediaType:   desc.MediaType,
+			Size:        desc.Size,
+			Digest:      desc.Digest,
+			Platform:    desc.Platform,
+			Annotations: map[string]string{common.RefNameAnnotation: common.LayoutRefName(repoName, newTag)},
+		}
+		if err := reg.Path.AppendDescriptor(tagged); err != nil {
+			return "", err
+		}
+	}
+	return descriptors[0].Digest.String(), nil
+}
+
+// DeleteTag removes the manifests of a tag from index.json, their blobs stay in the layout
+func (reg *LayoutRegistry) DeleteTag(ctx context.Context, repoName string, tag string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error) {
+	if strings.Contains(tag, ":") {
+		return nil, fmt.Errorf("%s is not a tag", tag)
+	}
+	entries := reg.tagEntries(repoName, tag)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("tag %s not found in repository %s", tag, repoName)
+	}
+	result := &common.DeleteResult{Repository: repoName, Reference: tag, Digest: entries[0].descriptor.Digest.String(), DryRun: opts.DryRun}
+	if opts.DryRun {
+		return result, nil
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	if err := reg.Path.RemoveDescriptors(reg.tagMatcher(repoName, tag)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteManifest removes all the tags of a manifest in repoName from index.json, its blobs stay in the layout
+func (reg *LayoutRegistry) DeleteManifest(ctx context.Context, repoName string, digest string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error) {
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a digest: %w", digest, err)
+	}
+	entries, err := reg.repositoryEntries(repoName)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, entry := range entries {
+		found = found || entry.descriptor.Digest == hash
+	}
+	if !found {
+		return nil, fmt.Errorf("manifest %s not found in repository %s", digest, repoName)
+	}
+	result := &common.DeleteResult{Repository: repoName, Reference: digest, Digest: digest, DryRun: opts.DryRun}
+	if opts.DryRun {
+		return result, nil
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	err = reg.Path.RemoveDescriptors(func(desc v1.Descriptor) bool {
+		entry, ok := reg.entryOf(desc)
+		return ok && entry.repository == repoName && desc.Digest == hash
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// tagEntries returns the manifests of a tag, nil if the tag or the repository does not exist
+func (reg *LayoutRegistry) tagEntries(repoName string, tag string) []entry {
+	entries, err := reg.repositoryEntries(repoName)
+	if err != nil {
+		return nil
+	}
+	tagEntries := []entry{}
+	for _, entry := range entries {
+		if entry.tag == tag {
+			tagEntries = append(tagEntries, entry)
+		}
+	}
+	return tagEntries
+}
+
+// tagMatcher matches the index.json manifests of a tag of repoName
+func (reg *LayoutRegistry) tagMatcher(repoName string, tag string) match.Matcher {
+	return func(desc v1.Descriptor) bool {
+		entry, ok := reg.entryOf(desc)
+		return ok && entry.repository == repoName && entry.tag == tag
+	}
+}
@@ -0,0 +1,67 @@
+package quay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/armosec/registryx/common"
+)
+
+// tokenURL returns the URL of the token service of the registry, discovered from the WWW-Authenticate challenge of /v2/
+// so self-hosted Quay (Red Hat Quay) registries are authenticated with their own token service.
+// The challenge is requested once per registry
+func (reg *QuayioRegistry) tokenURL(ctx context.Context) (string, error) {
+	reg.challengeLock.Lock()
+	defer reg.challengeLock.Unlock()
+	if reg.challenge == nil {
+		challenge, err := reg.discoverChallenge(ctx)
+		if err != nil {
+			return "", err
+		}
+		reg.challenge = challenge
+	}
+	realm, err := url.Parse(reg.challenge.Realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token service realm %s: %w", reg.challenge.Realm, err)
+	}
+	if reg.challenge.Service != "" {
+		query := realm.Query()
+		query.Set("service", reg.challenge.Service)
+		realm.RawQuery = query.Encode()
+	}
+	return realm.String(), nil
+}
+
+func (reg *QuayioRegistry) discoverChallenge(ctx context.Context) (*common.BearerChallenge, error) {
+	uri := &url.URL{Scheme: reg.GetRegistry().Scheme(), Host: reg.GetRegistry().RegistryStr(), Path: "/v2/"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := reg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	challenge, ok := common.ParseBearerChallenge(resp.Header.Get("WWW-Authenticate"))
+	if resp.StatusCode != http.StatusUnauthorized || !ok {
+		return nil, fmt.Errorf("registry %s did not send a bearer challenge (status %d)", reg.GetRegistry().RegistryStr(), resp.StatusCode)
+	}
+	return &challenge, nil
+}
+
+// hasRobotCredentials reports whether the registry is authenticated with a robot account (or a user) name and token,
+// which are exchanged for V2 tokens
+func (reg *QuayioRegistry) hasRobotCredentials() bool {
+	auth := reg.GetAuth()
+	return auth != nil && auth.Username != "" && auth.Password != ""
+}
+
+// addAPIAuthHeader authorizes an /api/v1 request with the OAuth application token set as the identity token
+func (reg *QuayioRegistry) addAPIAuthHeader(req *http.Request) {
+	if auth := reg.GetAuth(); auth != nil && auth.IdentityToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", auth.IdentityToken))
+	}
+}
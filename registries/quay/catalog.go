@@ -36,27 +36,25 @@ func catalogOptionsToQuery(uri *url.URL, pagination common.PaginationOption, opt
 
 	return uri
 }
-func (reg *QuayioRegistry) Catalog(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption, authenticator authn.Authenticator) ([]string, *common.PaginationOption, error) {
-	//if quay is invalid we use it as public!!!!
-	if err := common.ValidateAuth(reg.GetAuth()); err == nil {
-		if authenticator == nil {
-			authenticator = authn.FromConfig(*reg.GetAuth())
-		}
-
-		return reg.catalogQuayV2Auth(pagination, options)
-
-	} else {
-		options.IsPublic = true
 
+// Catalog lists the repositories with the V2 catalog when the registry has robot credentials: every repository the robot account
+// can see, whatever options.Namespaces. Otherwise it lists them with the quay API (see Repositories): the repositories of
+// options.Namespaces or of all the namespaces of the OAuth application token, or the public ones without a token
+func (reg *QuayioRegistry) Catalog(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption, authenticator authn.Authenticator) ([]string, *common.PaginationOption, error) {
+	if reg.hasRobotCredentials() {
+		return reg.catalogQuayV2Auth(ctx, pagination, options)
 	}
-
-	return reg.catalogQuayProprietery(pagination, options)
+	return reg.catalogQuayProprietery(ctx, pagination, options)
 }
 
-func (reg *QuayioRegistry) catalogQuayV2Auth(pagination common.PaginationOption, options common.CatalogOption) ([]string, *common.PaginationOption, error) {
+func (reg *QuayioRegistry) catalogQuayV2Auth(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption) ([]string, *common.PaginationOption, error) {
 
 	//Token Request
-	token, err := reg.GetV2Token(reg.HTTPClient, AUTH_URL)
+	tokenURL, err := reg.tokenURL(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := reg.GetV2Token(ctx, reg.HTTPClient, tokenURL)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -71,7 +69,7 @@ func (reg *QuayioRegistry) catalogQuayV2Auth(pagination common.PaginationOption,
 		q.Add("last", pagination.Cursor)
 	}
 	uri.RawQuery = q.Encode()
-	req, err := http.NewRequest(http.MethodGet, uri.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -103,40 +101,31 @@ func (reg *QuayioRegistry) catalogQuayV2Auth(pagination common.PaginationOption,
 	if err := json.NewDecoder(resp.Body).Decode(repos); err != nil {
 		return nil, nil, err
 	}
-	pgn := &common.PaginationOption{Size: pagination.Size}
-	if len(repos.Repositories) > 0 && pagination.Size > 0 {
-		pgn.Cursor = repos.Repositories[len(repos.Repositories)-1]
-	}
-
-	return repos.Repositories, pgn, nil
+	pgn, err := common.NextV2Pagination(resp, repos.Repositories, pagination.Size)
+	return repos.Repositories, pgn, err
 }
 
-func (reg *QuayioRegistry) catalogQuayProprietery(pagination common.PaginationOption, options common.CatalogOption) ([]string, *common.PaginationOption, error) {
-	data, err := reg.CatalogAux(pagination, options)
+func (reg *QuayioRegistry) catalogQuayProprietery(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption) ([]string, *common.PaginationOption, error) {
+	repositories, pgn, err := reg.Repositories(ctx, pagination, options)
 	if err != nil {
 		return nil, nil, err
 	}
-	repositories := data.Transform(pagination.Size)
-	var pgn *common.PaginationOption = nil
-	if data.Cursor != "" {
-		pgn = &common.PaginationOption{Cursor: data.Cursor, Size: pagination.Size}
-
-		repositories = append(repositories, data.Transform(0)...)
-
+	names := make([]string, 0, len(repositories))
+	for i := range repositories {
+		names = append(names, repositories[i].FullName())
 	}
-	return repositories, pgn, nil
+	return names, pgn, nil
 }
 
-func (reg *QuayioRegistry) CatalogAux(pagination common.PaginationOption, options common.CatalogOption) (*QuayCatalogResponse, error) {
+func (reg *QuayioRegistry) CatalogAux(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption) (*QuayCatalogResponse, error) {
 	uri := reg.getURL("repository")
 	uri = catalogOptionsToQuery(uri, pagination, options)
-	client := http.Client{}
-
-	req, err := http.NewRequest("GET", uri.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := client.Do(req)
+	reg.addAPIAuthHeader(req)
+	resp, err := reg.HTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,89 @@
+package quay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestQuay(t *testing.T, auth *authn.AuthConfig, handler http.HandlerFunc) (*QuayioRegistry, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	quayio, err := NewQuayIORegistry(auth, &registry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Quay))
+	assert.Nil(t, err)
+	return quayio.(*QuayioRegistry), server
+}
+
+func TestCatalogSelfHostedTokenService(t *testing.T) {
+	pings := &atomic.Int32{}
+	quayio, server := newTestQuay(t, &authn.AuthConfig{Username: "my-org+robot", Password: "robot-token"}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			pings.Add(1)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/v2/auth",service="quay.internal.corp"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/v2/auth":
+			assert.Equal(t, "quay.internal.corp", r.URL.Query().Get("service"))
+			username, password, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "my-org+robot", username)
+			assert.Equal(t, "robot-token", password)
+			w.Write([]byte(`{"token": "v2-token"}`))
+		case "/v2/_catalog":
+			assert.Equal(t, "Bearer v2-token", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"repositories": ["my-org/app", "my-org/db"]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	for range 2 {
+		repos, nextPage, err := quayio.Catalog(context.Background(), common.NoPaginationOption(), common.CatalogOption{}, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, nextPage)
+		assert.Equal(t, []string{"my-org/app", "my-org/db"}, repos)
+	}
+	//the token service is discovered once
+	assert.Equal(t, int32(1), pings.Load())
+}
+
+func TestCatalogWithoutBearerChallenge(t *testing.T) {
+	quayio, server := newTestQuay(t, &authn.AuthConfig{Username: "my-org+robot", Password: "robot-token"}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	_, _, err := quayio.Catalog(context.Background(), common.NoPaginationOption(), common.CatalogOption{}, nil)
+	assert.Error(t, err)
+}
+
+func TestCatalogOAuthToken(t *testing.T) {
+	quayio, server := newTestQuay(t, &authn.AuthConfig{IdentityToken: "oauth-token"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repository" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "Bearer oauth-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "my-org", r.URL.Query().Get("namespace"))
+		//private repositories are listed with the token
+		assert.Empty(t, r.URL.Query().Get("public"))
+		w.Write([]byte(`{"repositories": [{"namespace": "my-org", "name": "private-app", "is_public": false}]}`))
+	})
+	defer server.Close()
+
+	repos, _, err := quayio.Catalog(context.Background(), common.NoPaginationOption(), common.CatalogOption{Namespaces: "my-org"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"my-org/private-app"}, repos)
+}
@@ -1,5 +1,6 @@
 package quay
 
 const (
+	// Deprecated: the token service is discovered from the WWW-Authenticate challenge of the registry
 	AUTH_URL = "https://quay.io/v2/auth?service=quay.io"
 )
@@ -1,5 +1,10 @@
 package quay
 
+import (
+	"fmt"
+	"time"
+)
+
 // put here unique datastructures related to quay.io
 type QuayRepository struct {
 	Namespace    string `json:"namespace,omitempty"`
@@ -7,11 +12,85 @@ type QuayRepository struct {
 	Description  string `json:"description,omitempty"`
 	IsPublic     bool   `json:"is_public,omitempty"`
 	Kind         string `json:"kind,omitempty"`
-	State        string `json:"state,omitempty"`
-	LastModified int    `json:"last_modified,omitempty"`
+	State        string `json:"state,omitempty"`         // NORMAL, READ_ONLY or MIRROR
+	LastModified int    `json:"last_modified,omitempty"` // unix time, only set with CatalogOption.IncludeLastModified
+}
+
+// FullName returns the repository name with its namespace
+func (r *QuayRepository) FullName() string {
+	return fmt.Sprintf("%s/%s", r.Namespace, r.Name)
+}
+
+// LastModifiedTime returns the last modification time of the repository, zero if it was not requested
+func (r *QuayRepository) LastModifiedTime() time.Time {
+	if r.LastModified == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(r.LastModified), 0)
+}
+
+// QuayUser is the user of an OAuth application token
+type QuayUser struct {
+	Username      string `json:"username"`
+	Organizations []struct {
+		Name string `json:"name"`
+	} `json:"organizations"`
 }
 
 type QuayCatalogResponse struct {
 	Repositories []QuayRepository `json:"repositories"`
 	Cursor       string           `json:"next_page,omitempty"`
 }
+
+// QuayTag is a position of a tag in the tag history of a repository
+type QuayTag struct {
+	Name           string `json:"name"`
+	ManifestDigest string `json:"manifest_digest"`
+	IsManifestList bool   `json:"is_manifest_list,omitempty"`
+	Size           int64  `json:"size,omitempty"`
+	StartTs        int64  `json:"start_ts"`                // unix time the tag started pointing to the manifest
+	EndTs          int64  `json:"end_ts,omitempty"`        // unix time the tag was moved, deleted or expired, unset while the tag is active
+	Expiration     string `json:"expiration,omitempty"`    // RFC 1123 time the tag expires, unset without an expiration
+	LastModified   string `json:"last_modified,omitempty"` // RFC 1123 time of start_ts
+	Reversion      bool   `json:"reversion,omitempty"`     // the tag was restored to a previous manifest
+}
+
+// Start returns the time the tag started pointing to the manifest
+func (t *QuayTag) Start() time.Time {
+	return time.Unix(t.StartTs, 0)
+}
+
+// End returns the time the tag stopped pointing to the manifest, zero for active tags without an expiration
+func (t *QuayTag) End() time.Time {
+	if t.EndTs == 0 {
+		return time.Time{}
+	}
+	return time.Unix(t.EndTs, 0)
+}
+
+// ExpirationTime returns the expiration time of the tag, zero if the tag does not expire or the time cannot be parsed
+func (t *QuayTag) ExpirationTime() time.Time {
+	if t.Expiration == "" {
+		return time.Time{}
+	}
+	expiration, err := time.Parse(quayTimeLayout, t.Expiration)
+	if err != nil {
+		return time.Time{}
+	}
+	return expiration
+}
+
+// IsActive reports whether the tag points to the manifest at now, it is neither moved, deleted nor expired
+func (t *QuayTag) IsActive(now time.Time) bool {
+	if end := t.End(); !end.IsZero() && !end.After(now) {
+		return false
+	}
+	expiration := t.ExpirationTime()
+	return expiration.IsZero() || expiration.After(now)
+}
+
+type QuayTagsResponse struct {
+	Tags          []QuayTag `json:"tags"`
+	Page          int       `json:"page"`
+	HasAdditional bool      `json:"has_additional"`
+}
@@ -0,0 +1,41 @@
+package quay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// DeleteTag deletes a tag with the quay tags API (/api/v1/repository/{repository}/tag/{tag}),
+// the API is authorized with an OAuth application token set as the identity token
+func (reg *QuayioRegistry) DeleteTag(ctx context.Context, repoName string, tag string, opts common.DeleteOption, options ...remote.Option) (*common.DeleteResult, error) {
+	//resolve the manifest of the tag
+	result, err := reg.DefaultRegistry.DeleteTag(ctx, repoName, tag, common.DeleteOption{DryRun: true}, options...)
+	if err != nil {
+		return nil, err
+	}
+	result.DryRun = opts.DryRun
+	if opts.DryRun {
+		return result, nil
+	}
+	uri := reg.getURL(fmt.Sprintf("repository/%s/tag/%s", repoName, url.PathEscape(tag)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	reg.addAPIAuthHeader(req)
+	resp, err := reg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusNoContent, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package quay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteTag(t *testing.T) {
+	const digest = "sha256:4c5ff5f3be2ef1e4df37c2e7c3e9c9a3bd0b9f1b1d5d7c1a0e4d3b2a1f0e9d8c"
+	deleted := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead && r.URL.Path == "/v2/my-org/app/manifests/v1":
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Content-Length", "100")
+			w.Header().Set("Docker-Content-Digest", digest)
+		case r.Method == http.MethodHead && r.URL.Path == "/v2/my-org/app/manifests/v2":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/repository/my-org/app/tag/v1":
+			assert.Equal(t, "Bearer oauth-token", r.Header.Get("Authorization"))
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	quayio, err := NewQuayIORegistry(&authn.AuthConfig{IdentityToken: "oauth-token"}, &registry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Quay))
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	result, err := quayio.DeleteTag(ctx, "my-org/app", "v1", common.DeleteOption{DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, &common.DeleteResult{Repository: "my-org/app", Reference: "v1", Digest: digest, DryRun: true}, result)
+	assert.Empty(t, deleted)
+
+	result, err = quayio.DeleteTag(ctx, "my-org/app", "v1", common.DeleteOption{})
+	assert.Nil(t, err)
+	assert.False(t, result.DryRun)
+	assert.Equal(t, []string{"/api/v1/repository/my-org/app/tag/v1"}, deleted)
+
+	_, err = quayio.DeleteTag(ctx, "my-org/app", "v2", common.DeleteOption{})
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/armosec/registryx/common"
@@ -23,18 +24,21 @@ func NewQuayIORegistry(auth *authn.AuthConfig, registry *name.Registry, registry
 	}
 
 	reg := &QuayioRegistry{HTTPClient: &http.Client{Timeout: time.Duration(150) * time.Second},
-		DefaultRegistry: defaultregistry.DefaultRegistry{Registry: registry, Auth: auth}}
+		DefaultRegistry: defaultregistry.DefaultRegistry{Registry: registry, Auth: auth, Cfg: registryCfg}}
 	reg.This = reg
 	return reg, nil
 }
 
 func (reg *QuayioRegistry) GetMaxPageSize() int {
-	return 0
+	return QUAY_API_PAGE_SIZE
 }
 
 type QuayioRegistry struct {
 	defaultregistry.DefaultRegistry
 	HTTPClient *http.Client
+	// challenge is the token service of the registry, discovered on the first V2 token request
+	challenge     *common.BearerChallenge
+	challengeLock sync.Mutex
 }
 
 func (reg *QuayioRegistry) GetAuth() *authn.AuthConfig {
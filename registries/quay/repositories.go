@@ -0,0 +1,126 @@
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// QUAY_API_PAGE_SIZE is the page size of the quay API repositories listing, it cannot be changed by the client
+const QUAY_API_PAGE_SIZE = 100
+
+// Repositories lists a page of the repositories with their metadata from the quay API (/api/v1/repository),
+// a page holds all the repositories returned by quay (QUAY_API_PAGE_SIZE) whatever the pagination size.
+// The repositories are those of options.Namespaces, or of all the namespaces of the OAuth application token
+// (the cursor is then "<namespace>:<next_page>"), or the public ones without credentials.
+// The quay API does not authorize robot accounts, so without an OAuth token the repositories a robot account can see
+// are listed by the V2 catalog: only their namespace and name are set
+func (reg *QuayioRegistry) Repositories(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption) ([]QuayRepository, *common.PaginationOption, error) {
+	if !reg.hasOAuthToken() {
+		if reg.hasRobotCredentials() {
+			return reg.robotRepositories(ctx, pagination, options)
+		}
+		options.IsPublic = true
+	}
+	if options.Namespaces != "" || !reg.hasOAuthToken() {
+		data, err := reg.CatalogAux(ctx, pagination, options)
+		if err != nil {
+			return nil, nil, err
+		}
+		var pgn *common.PaginationOption
+		if data.Cursor != "" {
+			pgn = &common.PaginationOption{Cursor: data.Cursor, Size: pagination.Size}
+		}
+		return data.Repositories, pgn, nil
+	}
+	namespaces, err := reg.Namespaces(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(namespaces) == 0 {
+		return []QuayRepository{}, nil, nil
+	}
+	namespace, cursor := namespaces[0], ""
+	if pagination.Cursor != "" {
+		var found bool
+		if namespace, cursor, found = strings.Cut(pagination.Cursor, ":"); !found {
+			return nil, nil, fmt.Errorf("invalid pagination, cursor must be <namespace>:<next_page>")
+		}
+	}
+	current := slices.Index(namespaces, namespace)
+	if current == -1 {
+		return nil, nil, fmt.Errorf("invalid pagination, namespace %s is not visible", namespace)
+	}
+	options.Namespaces = namespace
+	data, err := reg.CatalogAux(ctx, common.PaginationOption{Cursor: cursor, Size: pagination.Size}, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pgn *common.PaginationOption
+	switch {
+	case data.Cursor != "":
+		pgn = &common.PaginationOption{Cursor: namespace + ":" + data.Cursor, Size: pagination.Size}
+	case current+1 < len(namespaces):
+		pgn = &common.PaginationOption{Cursor: namespaces[current+1] + ":", Size: pagination.Size}
+	}
+	return data.Repositories, pgn, nil
+}
+
+// robotRepositories lists a page of the V2 catalog of the robot account, the repositories outside options.Namespaces are skipped
+func (reg *QuayioRegistry) robotRepositories(ctx context.Context, pagination common.PaginationOption, options common.CatalogOption) ([]QuayRepository, *common.PaginationOption, error) {
+	names, pgn, err := reg.catalogQuayV2Auth(ctx, pagination, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	repositories := make([]QuayRepository, 0, len(names))
+	for _, fullName := range names {
+		namespace, name, found := strings.Cut(fullName, "/")
+		if !found || (options.Namespaces != "" && namespace != options.Namespaces) {
+			continue
+		}
+		repositories = append(repositories, QuayRepository{Namespace: namespace, Name: name})
+	}
+	return repositories, pgn, nil
+}
+
+// Namespaces returns the namespaces of the OAuth application token user (the quay API requires an OAuth token): its own namespace and its organizations
+func (reg *QuayioRegistry) Namespaces(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reg.getURL("user/").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	reg.addAPIAuthHeader(req)
+	resp, err := reg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	user := &QuayUser{}
+	if err := json.NewDecoder(resp.Body).Decode(user); err != nil {
+		return nil, err
+	}
+	namespaces := []string{}
+	if user.Username != "" {
+		namespaces = append(namespaces, user.Username)
+	}
+	for _, organization := range user.Organizations {
+		if !slices.Contains(namespaces, organization.Name) {
+			namespaces = append(namespaces, organization.Name)
+		}
+	}
+	return namespaces, nil
+}
+
+func (reg *QuayioRegistry) hasOAuthToken() bool {
+	auth := reg.GetAuth()
+	return auth != nil && auth.IdentityToken != ""
+}
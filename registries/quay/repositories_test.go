@@ -0,0 +1,133 @@
+package quay
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+)
+
+// repositoriesHandler serves two pages of my-org repositories and a single page of alice repositories
+func repositoriesHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/api/v1/user/":
+			assert.Equal(t, "Bearer oauth-token", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"username": "alice", "organizations": [{"name": "my-org"}, {"name": "alice"}]}`))
+		case r.URL.Path == "/api/v1/repository" && query.Get("namespace") == "my-org" && query.Get("next_page") == "":
+			w.Write([]byte(`{"repositories": [
+				{"namespace": "my-org", "name": "app", "description": "the app", "is_public": true, "kind": "image", "state": "NORMAL", "last_modified": 1704067200},
+				{"namespace": "my-org", "name": "db", "is_public": false, "kind": "image", "state": "READ_ONLY"}
+			], "next_page": "gAAAAABl"}`))
+		case r.URL.Path == "/api/v1/repository" && query.Get("namespace") == "my-org" && query.Get("next_page") == "gAAAAABl":
+			w.Write([]byte(`{"repositories": [{"namespace": "my-org", "name": "worker", "kind": "image", "state": "MIRROR"}]}`))
+		case r.URL.Path == "/api/v1/repository" && query.Get("namespace") == "alice":
+			w.Write([]byte(`{"repositories": [{"namespace": "alice", "name": "sandbox", "kind": "image", "state": "NORMAL"}]}`))
+		case r.URL.Path == "/api/v1/repository" && query.Get("public") == "true":
+			assert.Empty(t, r.Header.Get("Authorization"))
+			w.Write([]byte(`{"repositories": [{"namespace": "quay", "name": "busybox", "is_public": true}]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestRepositories(t *testing.T) {
+	quayio, server := newTestQuay(t, &authn.AuthConfig{IdentityToken: "oauth-token"}, repositoriesHandler(t))
+	defer server.Close()
+	ctx := context.Background()
+	options := common.CatalogOption{Namespaces: "my-org", IncludeLastModified: true}
+
+	repositories, nextPage, err := quayio.Repositories(ctx, common.MakePagination(quayio.GetMaxPageSize()), options)
+	assert.Nil(t, err)
+	assert.Equal(t, &common.PaginationOption{Cursor: "gAAAAABl", Size: QUAY_API_PAGE_SIZE}, nextPage)
+	assert.Len(t, repositories, 2)
+	assert.Equal(t, "my-org/app", repositories[0].FullName())
+	assert.Equal(t, "the app", repositories[0].Description)
+	assert.True(t, repositories[0].IsPublic)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), repositories[0].LastModifiedTime().UTC())
+	assert.Equal(t, "READ_ONLY", repositories[1].State)
+	assert.True(t, repositories[1].LastModifiedTime().IsZero())
+
+	//a page size smaller than the quay page does not drop or duplicate repositories
+	repos, nextPage, err := quayio.Catalog(ctx, common.MakePagination(1), options, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"my-org/app", "my-org/db"}, repos)
+	repos, nextPage, err = quayio.Catalog(ctx, *nextPage, options, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Equal(t, []string{"my-org/worker"}, repos)
+}
+
+func TestRepositoriesOfAllNamespaces(t *testing.T) {
+	quayio, server := newTestQuay(t, &authn.AuthConfig{IdentityToken: "oauth-token"}, repositoriesHandler(t))
+	defer server.Close()
+	ctx := context.Background()
+
+	namespaces, err := quayio.Namespaces(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice", "my-org"}, namespaces)
+
+	repos := []string{}
+	for page, nextPage, err := quayio.Catalog(ctx, common.MakePagination(quayio.GetMaxPageSize()), common.CatalogOption{}, nil); ; page, nextPage, err = quayio.Catalog(ctx, *nextPage, common.CatalogOption{}, nil) {
+		assert.Nil(t, err)
+		repos = append(repos, page...)
+		if nextPage == nil {
+			break
+		}
+	}
+	assert.Equal(t, []string{"alice/sandbox", "my-org/app", "my-org/db", "my-org/worker"}, repos)
+
+	_, _, err = quayio.Repositories(ctx, common.PaginationOption{Cursor: "gAAAAABl"}, common.CatalogOption{})
+	assert.Error(t, err)
+	_, _, err = quayio.Repositories(ctx, common.PaginationOption{Cursor: "other:"}, common.CatalogOption{})
+	assert.Error(t, err)
+}
+
+func TestPublicRepositories(t *testing.T) {
+	quayio, server := newTestQuay(t, nil, repositoriesHandler(t))
+	defer server.Close()
+
+	repos, nextPage, err := quayio.Catalog(context.Background(), common.NoPaginationOption(), common.CatalogOption{}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Equal(t, []string{"quay/busybox"}, repos)
+	assert.Equal(t, QUAY_API_PAGE_SIZE, quayio.GetMaxPageSize())
+}
+
+func TestRobotRepositories(t *testing.T) {
+	quayio, server := newTestQuay(t, &authn.AuthConfig{Username: "my-org+robot", Password: "robot-token"}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/v2/auth",service="quay.internal.corp"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/v2/auth":
+			w.Write([]byte(`{"token": "v2-token"}`))
+		case "/v2/_catalog":
+			assert.Equal(t, "Bearer v2-token", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"repositories": ["my-org/app", "partner/db"]}`))
+		default:
+			//the quay API does not authorize robot accounts
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	//every namespace the robot account can see, including private repositories
+	repositories, nextPage, err := quayio.Repositories(ctx, common.NoPaginationOption(), common.CatalogOption{})
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	assert.Equal(t, []QuayRepository{{Namespace: "my-org", Name: "app"}, {Namespace: "partner", Name: "db"}}, repositories)
+
+	repositories, _, err = quayio.Repositories(ctx, common.NoPaginationOption(), common.CatalogOption{Namespaces: "partner"})
+	assert.Nil(t, err)
+	assert.Equal(t, []QuayRepository{{Namespace: "partner", Name: "db"}}, repositories)
+}
@@ -0,0 +1,44 @@
+package quay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// Retag points newTag at the manifest of reference with the quay tags API (/api/v1/repository/{repository}/tag/{tag}),
+// the API is authorized with an OAuth application token set as the identity token
+func (reg *QuayioRegistry) Retag(ctx context.Context, repoName string, reference string, newTag string, options ...remote.Option) (string, error) {
+	digest, err := reg.DefaultRegistry.Resolve(ctx, repoName, reference, options...)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(struct {
+		ManifestDigest string `json:"manifest_digest"`
+	}{ManifestDigest: digest})
+	if err != nil {
+		return "", err
+	}
+	uri := reg.getURL(fmt.Sprintf("repository/%s/tag/%s", repoName, url.PathEscape(newTag)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uri.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	reg.addAPIAuthHeader(req)
+	resp, err := reg.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusCreated, http.StatusOK); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
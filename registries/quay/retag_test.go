@@ -0,0 +1,51 @@
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetag(t *testing.T) {
+	const digest = "sha256:4c5ff5f3be2ef1e4df37c2e7c3e9c9a3bd0b9f1b1d5d7c1a0e4d3b2a1f0e9d8c"
+	tagged := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead && r.URL.Path == "/v2/my-org/app/manifests/sha-abc":
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Content-Length", "100")
+			w.Header().Set("Docker-Content-Digest", digest)
+		case r.Method == http.MethodPut && r.URL.Path == "/api/v1/repository/my-org/app/tag/1.4.2":
+			assert.Equal(t, "Bearer oauth-token", r.Header.Get("Authorization"))
+			body := struct {
+				ManifestDigest string `json:"manifest_digest"`
+			}{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			tagged["1.4.2"] = body.ManifestDigest
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	quayio, err := NewQuayIORegistry(&authn.AuthConfig{IdentityToken: "oauth-token"}, &registry, common.MakeRegistryOptions(false, true, false, "", "", "", common.Quay))
+	assert.Nil(t, err)
+
+	retagged, err := quayio.Retag(context.Background(), "my-org/app", "sha-abc", "1.4.2")
+	assert.Nil(t, err)
+	assert.Equal(t, digest, retagged)
+	assert.Equal(t, map[string]string{"1.4.2": digest}, tagged)
+}
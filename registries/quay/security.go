@@ -0,0 +1,184 @@
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// quay security scan statuses
+const (
+	securityStatusQueued      = "queued"
+	securityStatusScanned     = "scanned"
+	securityStatusFailed      = "failed"
+	securityStatusUnsupported = "unsupported"
+)
+
+// ClairScanner is the scanner of the quay security reports
+const ClairScanner = "Clair"
+
+// QuaySecurityReport is the Clair report of a manifest, Data is set only for scanned manifests
+type QuaySecurityReport struct {
+	ScanStatus string            `json:"status"`
+	Data       *QuaySecurityData `json:"data"`
+}
+
+type QuaySecurityData struct {
+	Layer QuaySecurityLayer `json:"Layer"`
+}
+
+type QuaySecurityLayer struct {
+	Name             string        `json:"Name"`
+	NamespaceName    string        `json:"NamespaceName"` // the distribution of the image, e.g "debian:12"
+	IndexedByVersion int           `json:"IndexedByVersion"`
+	Features         []QuayFeature `json:"Features"`
+}
+
+// QuayFeature is a package of the image with its vulnerabilities
+type QuayFeature struct {
+	Name            string                     `json:"Name"`
+	Version         string                     `json:"Version"`
+	VersionFormat   string                     `json:"VersionFormat"`
+	NamespaceName   string                     `json:"NamespaceName"`
+	AddedBy         string                     `json:"AddedBy"` // digest of the layer adding the package
+	BaseScores      []float64                  `json:"BaseScores"`
+	CVEIds          []string                   `json:"CVEIds"`
+	Vulnerabilities []QuayFeatureVulnerability `json:"Vulnerabilities"`
+}
+
+type QuayFeatureVulnerability struct {
+	Name          string                     `json:"Name"`
+	NamespaceName string                     `json:"NamespaceName"`
+	Description   string                     `json:"Description"`
+	Link          string                     `json:"Link"` // space separated links
+	Severity      string                     `json:"Severity"`
+	FixedBy       string                     `json:"FixedBy"`
+	Metadata      *QuayVulnerabilityMetadata `json:"Metadata"`
+}
+
+type QuayVulnerabilityMetadata struct {
+	RepoName      string `json:"RepoName"`
+	DistroName    string `json:"DistroName"`
+	DistroVersion string `json:"DistroVersion"`
+	NVD           struct {
+		CVSSv3 *QuayCVSS `json:"CVSSv3"`
+		CVSSv2 *QuayCVSS `json:"CVSSv2"`
+	} `json:"NVD"`
+}
+
+type QuayCVSS struct {
+	Vectors string  `json:"Vectors"`
+	Score   float64 `json:"Score"`
+}
+
+// Status returns the provider neutral status of the quay scan
+func (r *QuaySecurityReport) Status() common.ScanStatus {
+	switch r.ScanStatus {
+	case securityStatusQueued:
+		return common.ScanStatusQueued
+	case securityStatusScanned:
+		return common.ScanStatusScanned
+	case securityStatusFailed:
+		return common.ScanStatusFailed
+	case securityStatusUnsupported:
+		return common.ScanStatusUnsupported
+	default:
+		return common.ScanStatusNotScanned
+	}
+}
+
+// Features returns the packages found by Clair, nil if the manifest is not scanned
+func (r *QuaySecurityReport) Features() []QuayFeature {
+	if r.Data == nil {
+		return nil
+	}
+	return r.Data.Layer.Features
+}
+
+// SecurityReport returns the Clair report of a manifest (/api/v1/repository/{repo}/manifest/{digest}/security)
+// with the vulnerabilities of its packages
+func (reg *QuayioRegistry) SecurityReport(ctx context.Context, repoName string, digest string) (*QuaySecurityReport, error) {
+	uri := reg.getURL(fmt.Sprintf("repository/%s/manifest/%s/security", repoName, digest))
+	uri.RawQuery = "vulnerabilities=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	reg.addAPIAuthHeader(req)
+	resp, err := reg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	report := &QuaySecurityReport{}
+	if err := json.NewDecoder(resp.Body).Decode(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// VulnerabilityReport returns the normalized Clair report of a manifest, reference is a tag or a manifest digest.
+// Quay reports carry no generation time, so GeneratedAt is left zero and the reports are never fresh (see common.VulnerabilityReport.IsFresh).
+// Only the vulnerable packages are normalized, every package found by Clair is listed by QuaySecurityReport.Features
+func (reg *QuayioRegistry) VulnerabilityReport(ctx context.Context, repoName string, reference string) (*common.VulnerabilityReport, error) {
+	digest, err := reg.manifestDigest(ctx, repoName, reference)
+	if err != nil {
+		return nil, err
+	}
+	quayReport, err := reg.SecurityReport(ctx, repoName, digest)
+	if err != nil {
+		return nil, err
+	}
+	report := &common.VulnerabilityReport{Digest: digest, Status: quayReport.Status(), Vulnerabilities: []common.Vulnerability{}}
+	if report.Status != common.ScanStatusScanned {
+		return report, nil
+	}
+	report.Scanner = ClairScanner
+	for _, feature := range quayReport.Features() {
+		for _, vulnerability := range feature.Vulnerabilities {
+			normalized := common.Vulnerability{
+				ID:          vulnerability.Name,
+				Package:     feature.Name,
+				Version:     feature.Version,
+				FixVersion:  vulnerability.FixedBy,
+				Severity:    common.ParseSeverity(vulnerability.Severity),
+				Description: vulnerability.Description,
+				Links:       strings.Fields(vulnerability.Link),
+			}
+			if metadata := vulnerability.Metadata; metadata != nil {
+				if metadata.NVD.CVSSv3 != nil {
+					normalized.CVSSScore = metadata.NVD.CVSSv3.Score
+				} else if metadata.NVD.CVSSv2 != nil {
+					normalized.CVSSScore = metadata.NVD.CVSSv2.Score
+				}
+			}
+			report.Vulnerabilities = append(report.Vulnerabilities, normalized)
+		}
+	}
+	return report, nil
+}
+
+// manifestDigest resolves a tag to the digest of its active manifest, digests are returned as is
+func (reg *QuayioRegistry) manifestDigest(ctx context.Context, repoName string, reference string) (string, error) {
+	if strings.Contains(reference, ":") {
+		return reference, nil
+	}
+	tags, _, err := reg.tagsPage(ctx, repoName, common.MakePagination(1), true, reference)
+	if err != nil {
+		return "", err
+	}
+	for _, tag := range tags {
+		if tag.Name == reference && tag.ManifestDigest != "" {
+			return tag.ManifestDigest, nil
+		}
+	}
+	return "", fmt.Errorf("tag %s not found in repository %s", reference, repoName)
+}
@@ -0,0 +1,85 @@
+package quay
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed fixtures/securityResponse.json
+var securityResponse []byte
+
+func securityHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer oauth-token", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case tagsPath:
+			assert.Equal(t, "latest", r.URL.Query().Get("specificTag"))
+			assert.Equal(t, "true", r.URL.Query().Get("onlyActiveTags"))
+			w.Write([]byte(`{"page": 1, "has_additional": false, "tags": [{"name": "latest", "manifest_digest": "sha256:bbb", "start_ts": 1704153600}]}`))
+		case "/api/v1/repository/my-org/app/manifest/sha256:bbb/security":
+			assert.Equal(t, "true", r.URL.Query().Get("vulnerabilities"))
+			w.Write(securityResponse)
+		case "/api/v1/repository/my-org/app/manifest/sha256:aaa/security":
+			w.Write([]byte(`{"status": "queued", "data": null}`))
+		case "/api/v1/repository/my-org/app/manifest/sha256:ccc/security":
+			w.Write([]byte(`{"status": "unsupported", "data": null}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestVulnerabilityReport(t *testing.T) {
+	quayio, server := newTestQuay(t, &authn.AuthConfig{IdentityToken: "oauth-token"}, securityHandler(t))
+	defer server.Close()
+	ctx := context.Background()
+
+	report, err := quayio.VulnerabilityReport(ctx, "my-org/app", "latest")
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:bbb", report.Digest)
+	assert.Equal(t, common.ScanStatusScanned, report.Status)
+	assert.Equal(t, ClairScanner, report.Scanner)
+	//quay does not report when the vulnerabilities were matched
+	assert.True(t, report.GeneratedAt.IsZero())
+	assert.False(t, report.IsFresh(time.Hour))
+	assert.Len(t, report.Vulnerabilities, 3)
+	assert.Equal(t, common.Vulnerability{
+		ID:          "CVE-2023-5678",
+		Package:     "openssl",
+		Version:     "3.0.11-1~deb12u1",
+		FixVersion:  "3.0.13-1~deb12u1",
+		Severity:    common.SeverityHigh,
+		CVSSScore:   7.5,
+		Description: "Generating excessively long X9.42 DH keys may be slow.",
+		Links:       []string{"https://security-tracker.debian.org/tracker/CVE-2023-5678", "https://www.cve.org/CVERecord?id=CVE-2023-5678"},
+	}, report.Vulnerabilities[0])
+	//CVSS v2 is used without a v3 score
+	assert.Equal(t, 5.0, report.Vulnerabilities[1].CVSSScore)
+	assert.Empty(t, report.Vulnerabilities[1].Links)
+	assert.Equal(t, common.SeverityNegligible, report.Vulnerabilities[2].Severity)
+	assert.Equal(t, map[common.Severity]int{common.SeverityHigh: 1, common.SeverityMedium: 1, common.SeverityNegligible: 1}, report.Summary())
+	assert.Len(t, report.Fixable(), 1)
+
+	quayReport, err := quayio.SecurityReport(ctx, "my-org/app", "sha256:bbb")
+	assert.Nil(t, err)
+	assert.Len(t, quayReport.Features(), 3)
+	assert.Equal(t, "debian:12", quayReport.Features()[2].NamespaceName)
+
+	for digest, status := range map[string]common.ScanStatus{"sha256:aaa": common.ScanStatusQueued, "sha256:ccc": common.ScanStatusUnsupported} {
+		report, err := quayio.VulnerabilityReport(ctx, "my-org/app", digest)
+		assert.Nil(t, err)
+		assert.Equal(t, status, report.Status)
+		assert.Empty(t, report.Vulnerabilities)
+		assert.False(t, report.IsFresh(time.Hour))
+	}
+
+	_, err = quayio.VulnerabilityReport(ctx, "my-org/app", "sha256:ddd")
+	assert.Error(t, err)
+}
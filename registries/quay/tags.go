@@ -0,0 +1,151 @@
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// quayTimeLayout is the layout of the tag last_modified and expiration fields
+const quayTimeLayout = time.RFC1123Z
+
+// Tags lists a page of the tag history of a repository from the quay API (/api/v1/repository/{repo}/tag/),
+// the cursor is the page number and the page size is at most QUAY_API_PAGE_SIZE.
+// Without a pagination size all the pages are listed. With onlyActiveTags the expired and deleted tags are skipped,
+// otherwise every past position of a tag is listed with its end time
+func (reg *QuayioRegistry) Tags(ctx context.Context, repoName string, pagination common.PaginationOption, onlyActiveTags bool) ([]QuayTag, *common.PaginationOption, error) {
+	if pagination.Size > 0 {
+		return reg.tagsPage(ctx, repoName, pagination, onlyActiveTags, "")
+	}
+	tags := []QuayTag{}
+	for page, nextPage, err := reg.tagsPage(ctx, repoName, common.MakePagination(QUAY_API_PAGE_SIZE), onlyActiveTags, ""); ; page, nextPage, err = reg.tagsPage(ctx, repoName, *nextPage, onlyActiveTags, "") {
+		if err != nil {
+			return nil, nil, err
+		}
+		tags = append(tags, page...)
+		if nextPage == nil {
+			return tags, nil, nil
+		}
+	}
+}
+
+// tagsPage lists a page of the tag history, only the positions of specificTag when it is set
+func (reg *QuayioRegistry) tagsPage(ctx context.Context, repoName string, pagination common.PaginationOption, onlyActiveTags bool, specificTag string) ([]QuayTag, *common.PaginationOption, error) {
+	if pagination.Cursor == "" {
+		pagination.Cursor = "1"
+	}
+	page, err := strconv.Atoi(pagination.Cursor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid pagination, cursor must be an integer")
+	}
+	uri := reg.getURL(fmt.Sprintf("repository/%s/tag/", repoName))
+	query := uri.Query()
+	query.Set("page", pagination.Cursor)
+	query.Set("limit", strconv.Itoa(min(pagination.Size, QUAY_API_PAGE_SIZE)))
+	if onlyActiveTags {
+		query.Set("onlyActiveTags", "true")
+	}
+	if specificTag != "" {
+		query.Set("specificTag", specificTag)
+	}
+	uri.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	reg.addAPIAuthHeader(req)
+	resp, err := reg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, nil, err
+	}
+	data := &QuayTagsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
+		return nil, nil, err
+	}
+	tags := data.Tags
+	if onlyActiveTags {
+		//older quay versions ignore onlyActiveTags, and tags may expire after the request
+		now := time.Now()
+		tags = []QuayTag{}
+		for _, tag := range data.Tags {
+			if tag.IsActive(now) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	var nextPage *common.PaginationOption
+	if data.HasAdditional {
+		nextPage = &common.PaginationOption{Cursor: strconv.Itoa(page + 1), Size: pagination.Size}
+	}
+	return tags, nextPage, nil
+}
+
+// GetLatestImages ranks the images by the tag history of quay without fetching the image configs,
+// by default the most recently pushed images first (a manifest is as recent as its most recent tag).
+// Only the active tags are ranked. Without a strategy and with depth 1 the "latest" tag is returned if it is active, like the default registry does.
+// The tag history holds neither the image creation time nor the platforms of manifest lists, so strategies ranking by creation time,
+// an explicit platform, and repositories the quay API cannot list (e.g V2 robot credentials of a private repository), are handled by the default registry
+func (reg *QuayioRegistry) GetLatestImages(ctx context.Context, repoName string, depth int, opts common.LatestTagsOption, options ...remote.Option) ([]common.TaggedImage, error) {
+	if !opts.Platform.IsDefault() || (opts.Strategy != nil && !common.RanksByPushTime(opts.Strategy)) {
+		return reg.DefaultRegistry.GetLatestImages(ctx, repoName, depth, opts, options...)
+	}
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = common.PushedStrategy()
+	}
+	tags, _, err := reg.Tags(ctx, repoName, common.NoPaginationOption(), true)
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && (transportErr.StatusCode == http.StatusUnauthorized ||
+			transportErr.StatusCode == http.StatusForbidden || transportErr.StatusCode == http.StatusNotFound) {
+			return reg.DefaultRegistry.GetLatestImages(ctx, repoName, depth, opts, options...)
+		}
+		return nil, err
+	}
+	imagesByDigest := map[string]*common.TaggedImage{}
+	latestImages := []*common.TaggedImage{}
+	for _, tag := range tags {
+		if tag.ManifestDigest == "" {
+			continue
+		}
+		//if depth is one (default) and latest tag found no need to rank
+		if depth == 1 && opts.Strategy == nil && tag.Name == "latest" && reg.Cfg.TagFilter().Matches(tag.Name) && opts.Filter.Matches(tag.Name) {
+			return []common.TaggedImage{{Digest: tag.ManifestDigest, Tags: []string{"latest"}, Pushed: tag.Start()}}, nil
+		}
+		if !strategy.Accepts(tag.Name) || !reg.Cfg.TagFilter().Matches(tag.Name) || !opts.Filter.Matches(tag.Name) {
+			continue
+		}
+		image, ok := imagesByDigest[tag.ManifestDigest]
+		if !ok {
+			image = &common.TaggedImage{Digest: tag.ManifestDigest}
+			imagesByDigest[tag.ManifestDigest] = image
+			latestImages = append(latestImages, image)
+		}
+		image.Tags = append(image.Tags, tag.Name)
+		if start := tag.Start(); start.After(image.Pushed) {
+			image.Pushed = start
+		}
+	}
+	sort.SliceStable(latestImages, func(i, j int) bool {
+		return strategy.NewerImage(latestImages[i], latestImages[j])
+	})
+	images := []common.TaggedImage{}
+	for _, image := range latestImages[:min(depth, len(latestImages))] {
+		common.SortImageTags(image.Tags)
+		images = append(images, *image)
+	}
+	return images, nil
+}
@@ -0,0 +1,182 @@
+package quay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+const tagsPath = "/api/v1/repository/my-org/app/tag/"
+
+// tagsHandler serves two pages of the my-org/app tag history, the page size is ignored like quay does for larger limits
+func tagsHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tagsPath {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "Bearer oauth-token", r.Header.Get("Authorization"))
+		query := r.URL.Query()
+		assert.Equal(t, "100", query.Get("limit"))
+		active := query.Get("onlyActiveTags") == "true"
+		switch query.Get("page") {
+		case "1":
+			if active {
+				w.Write([]byte(`{"page": 1, "has_additional": true, "tags": [
+					{"name": "latest", "manifest_digest": "sha256:bbb", "start_ts": 1704153600, "size": 2048},
+					{"name": "v1.1", "manifest_digest": "sha256:bbb", "start_ts": 1704067200, "size": 2048},
+					{"name": "expired", "manifest_digest": "sha256:ddd", "start_ts": 1700000000, "expiration": "Wed, 01 Nov 2023 00:00:00 -0000"}
+				]}`))
+				return
+			}
+			w.Write([]byte(`{"page": 1, "has_additional": true, "tags": [
+				{"name": "latest", "manifest_digest": "sha256:bbb", "start_ts": 1704153600, "size": 2048},
+				{"name": "latest", "manifest_digest": "sha256:aaa", "start_ts": 1701388800, "end_ts": 1704153600, "last_modified": "Fri, 01 Dec 2023 00:00:00 -0000"}
+			]}`))
+		case "2":
+			w.Write([]byte(`{"page": 2, "has_additional": false, "tags": [
+				{"name": "v1.0", "manifest_digest": "sha256:aaa", "start_ts": 1701388800, "is_manifest_list": true},
+				{"name": "dev", "manifest_digest": "sha256:ccc", "start_ts": 1704240000}
+			]}`))
+		default:
+			t.Errorf("unexpected page %s", query.Get("page"))
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	quayio, server := newTestQuay(t, &authn.AuthConfig{IdentityToken: "oauth-token"}, tagsHandler(t))
+	defer server.Close()
+	ctx := context.Background()
+
+	tags, nextPage, err := quayio.Tags(ctx, "my-org/app", common.MakePagination(quayio.GetMaxPageSize()), false)
+	assert.Nil(t, err)
+	assert.Equal(t, &common.PaginationOption{Cursor: "2", Size: QUAY_API_PAGE_SIZE}, nextPage)
+	assert.Len(t, tags, 2)
+	assert.Equal(t, "sha256:bbb", tags[0].ManifestDigest)
+	assert.Equal(t, int64(2048), tags[0].Size)
+	assert.True(t, tags[0].End().IsZero())
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), tags[0].Start().UTC())
+	//the previous position of latest
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), tags[1].End().UTC())
+	assert.False(t, tags[1].IsActive(time.Now()))
+
+	//all the pages of the active tags, the expired tag is skipped
+	tags, nextPage, err = quayio.Tags(ctx, "my-org/app", common.NoPaginationOption(), true)
+	assert.Nil(t, err)
+	assert.Nil(t, nextPage)
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	assert.Equal(t, []string{"latest", "v1.1", "v1.0", "dev"}, names)
+	assert.True(t, tags[2].IsManifestList)
+
+	_, _, err = quayio.Tags(ctx, "my-org/app", common.PaginationOption{Cursor: "next", Size: 10}, false)
+	assert.Error(t, err)
+}
+
+func TestQuayTagIsActive(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, (&QuayTag{StartTs: 1700000000}).IsActive(now))
+	assert.True(t, (&QuayTag{Expiration: "Tue, 02 Jan 2024 00:00:00 -0000"}).IsActive(now))
+	assert.False(t, (&QuayTag{Expiration: "Sun, 31 Dec 2023 00:00:00 -0000"}).IsActive(now))
+	//quay sets the end time of a tag with an expiration
+	assert.True(t, (&QuayTag{EndTs: now.Add(time.Hour).Unix()}).IsActive(now))
+	assert.False(t, (&QuayTag{EndTs: now.Unix()}).IsActive(now))
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), (&QuayTag{Expiration: "Tue, 02 Jan 2024 00:00:00 -0000"}).ExpirationTime().UTC())
+	assert.True(t, (&QuayTag{Expiration: "soon"}).ExpirationTime().IsZero())
+}
+
+func TestGetLatestImagesFromTagHistory(t *testing.T) {
+	quayio, server := newTestQuay(t, &authn.AuthConfig{IdentityToken: "oauth-token"}, tagsHandler(t))
+	defer server.Close()
+	ctx := context.Background()
+
+	images, err := quayio.GetLatestImages(ctx, "my-org/app", 3, common.LatestTagsOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, []common.TaggedImage{
+		{Digest: "sha256:ccc", Tags: []string{"dev"}, Pushed: time.Unix(1704240000, 0)},
+		{Digest: "sha256:bbb", Tags: []string{"latest", "v1.1"}, Pushed: time.Unix(1704153600, 0)},
+		{Digest: "sha256:aaa", Tags: []string{"v1.0"}, Pushed: time.Unix(1701388800, 0)},
+	}, images)
+
+	tags, err := quayio.GetLatestTags("my-org/app", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dev", "latest,v1.1"}, tags)
+
+	//like the default registry, the latest tag is returned for depth 1 without a strategy
+	images, err = quayio.GetLatestImages(ctx, "my-org/app", 1, common.LatestTagsOption{})
+	assert.Nil(t, err)
+	assert.Equal(t, []common.TaggedImage{{Digest: "sha256:bbb", Tags: []string{"latest"}, Pushed: time.Unix(1704153600, 0)}}, images)
+	images, err = quayio.GetLatestImages(ctx, "my-org/app", 1, common.LatestTagsOption{Strategy: common.PushedStrategy()})
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:ccc", images[0].Digest)
+
+	strategy, err := common.SemverConstraintStrategy(">= 1.0")
+	assert.Nil(t, err)
+	images, err = quayio.GetLatestImages(ctx, "my-org/app", 1, common.LatestTagsOption{Strategy: strategy})
+	assert.Nil(t, err)
+	assert.Len(t, images, 1)
+	assert.Equal(t, []string{"v1.1"}, images[0].Tags)
+}
+
+func TestGetLatestImagesByCreationTime(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v2 := registry.New()
+	var pushed map[string]string
+	quayio, server := newTestQuay(t, &authn.AuthConfig{IdentityToken: "oauth-token"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tagsPath {
+			v2.ServeHTTP(w, r)
+			return
+		}
+		//old was pushed last but created first
+		w.Write([]byte(fmt.Sprintf(`{"page": 1, "has_additional": false, "tags": [
+			{"name": "old", "manifest_digest": "%s", "start_ts": 1704240000},
+			{"name": "new", "manifest_digest": "%s", "start_ts": 1704153600}
+		]}`, pushed["old"], pushed["new"])))
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	pushed = map[string]string{}
+	for tag, created := range map[string]time.Time{"old": base, "new": base.Add(time.Hour)} {
+		image, err := random.Image(256, 1)
+		assert.Nil(t, err)
+		image, err = mutate.CreatedAt(image, v1.Time{Time: created})
+		assert.Nil(t, err)
+		ref, err := name.ParseReference(fmt.Sprintf("%s/my-org/app:%s", quayio.Registry.Name(), tag), name.Insecure)
+		assert.Nil(t, err)
+		assert.Nil(t, remote.Write(ref, image))
+		digest, err := image.Digest()
+		assert.Nil(t, err)
+		pushed[tag] = digest.String()
+	}
+
+	//the tag history has no creation time, the images are ranked by their configs
+	images, err := quayio.GetLatestImages(ctx, "my-org/app", 2, common.LatestTagsOption{Strategy: common.CreatedStrategy()})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"new"}, images[0].Tags)
+	assert.Equal(t, base.Add(time.Hour), images[0].Created.UTC())
+
+	images, err = quayio.GetLatestImages(ctx, "my-org/app", 2, common.LatestTagsOption{Strategy: common.PushedStrategy()})
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, []string{"old"}, images[0].Tags)
+	assert.True(t, images[0].Created.IsZero())
+}
@@ -0,0 +1,43 @@
+package registries
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// RemoteOptions binds remote calls to ctx and sends them with the authenticator and transport of the registry
+func RemoteOptions(ctx context.Context, registry interfaces.IRegistry) []remote.Option {
+	options := []remote.Option{remote.WithContext(ctx), remote.WithAuth(Authenticator(registry))}
+	if cfg := registryOptions(registry); cfg != nil && cfg.SkipTLSVerify() {
+		options = append(options, remote.WithTransport(cfg.Transport()))
+	}
+	return options
+}
+
+// Authenticator returns the authenticator of the registry credentials, anonymous if there are none
+func Authenticator(registry interfaces.IRegistry) authn.Authenticator {
+	if auth := registry.GetAuth(); common.ValidateAuth(auth) == nil {
+		return authn.FromConfig(*auth)
+	}
+	return authn.Anonymous
+}
+
+// Transport returns the transport of the registry options, TLS certificates are not verified with SkipTLSVerify
+func Transport(registry interfaces.IRegistry) http.RoundTripper {
+	return registryOptions(registry).Transport()
+}
+
+// registryOptions returns the options of registries exposing them, nil otherwise
+func registryOptions(registry interfaces.IRegistry) *common.RegistryOptions {
+	if configured, ok := registry.(interface {
+		GetRegistryOptions() *common.RegistryOptions
+	}); ok {
+		return configured.GetRegistryOptions()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package registries
+
+import (
+	"context"
+	"sync"
+
+	"github.com/armosec/registryx/interfaces"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const defaultResolveWorkers = 10
+
+// ResolveRequest is a tag (or a digest) in a repository to resolve
+type ResolveRequest struct {
+	Repository string
+	Reference  string
+}
+
+type ResolveResult struct {
+	Repository string
+	Reference  string
+	Digest     string
+	Err        error
+}
+
+// ResolveAll resolves references to digests concurrently using at most workers parallel requests,
+// results are returned in the order of the requests and each one carries its own error
+func ResolveAll(ctx context.Context, registry interfaces.IRegistry, requests []ResolveRequest, workers int, options ...remote.Option) []ResolveResult {
+	if workers <= 0 {
+		workers = defaultResolveWorkers
+	}
+	results := make([]ResolveResult, len(requests))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for range min(workers, len(requests)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				request := requests[i]
+				digest, err := registry.Resolve(ctx, request.Repository, request.Reference, options...)
+				results[i] = ResolveResult{Repository: request.Repository, Reference: request.Reference, Digest: digest, Err: err}
+			}
+		}()
+	}
+	for i := range requests {
+		if ctx.Err() != nil {
+			results[i] = ResolveResult{Repository: requests[i].Repository, Reference: requests[i].Reference, Err: ctx.Err()}
+			continue
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
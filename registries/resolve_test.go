@@ -0,0 +1,47 @@
+package registries
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/registryx/common"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveAll(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	reg, err := Factory(nil, strings.TrimPrefix(server.URL, "http://"), common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	repo, err := common.MakeRepoWithRegistry("my-repo", reg.GetRegistry())
+	assert.Nil(t, err)
+
+	requests := []ResolveRequest{}
+	digests := []string{}
+	for i := range 5 {
+		image, err := random.Image(32, 1)
+		assert.Nil(t, err)
+		tag := fmt.Sprintf("v%d", i)
+		assert.Nil(t, remote.Write(repo.Tag(tag), image))
+		digest, err := image.Digest()
+		assert.Nil(t, err)
+		requests = append(requests, ResolveRequest{Repository: "my-repo", Reference: tag})
+		digests = append(digests, digest.String())
+	}
+	requests = append(requests, ResolveRequest{Repository: "my-repo", Reference: "missing"})
+
+	results := ResolveAll(context.Background(), reg, requests, 2)
+	assert.Len(t, results, 6)
+	for i, digest := range digests {
+		assert.Nil(t, results[i].Err)
+		assert.Equal(t, requests[i].Reference, results[i].Reference)
+		assert.Equal(t, digest, results[i].Digest)
+	}
+	assert.Error(t, results[5].Err)
+}
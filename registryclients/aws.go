@@ -135,7 +135,7 @@ func (a *AWSRegistryClient) GetAllRepositories(ctx context.Context) ([]string, e
 	return getAllRepositories(ctx, iRegistry)
 }
 
-func (a *AWSRegistryClient) GetImagesToScan(_ context.Context) (map[string]string, error) {
+func (a *AWSRegistryClient) GetImagesToScan(ctx context.Context) (map[string]string, error) {
 	registry, err := name.NewRegistry(a.registryURI)
 	if err != nil {
 		return nil, err
@@ -146,7 +146,7 @@ func (a *AWSRegistryClient) GetImagesToScan(_ context.Context) (map[string]strin
 	}
 	images := make(map[string]string, len(a.Registry.Repositories))
 	for _, repository := range a.Registry.Repositories {
-		tag, err := getImageLatestTag(repository, iRegistry)
+		tag, err := getImageLatestTag(ctx, repository, iRegistry, a.Options)
 		if err != nil {
 			return nil, err
 		}
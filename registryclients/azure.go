@@ -29,7 +29,7 @@ func (a *AzureRegistryClient) GetAllRepositories(ctx context.Context) ([]string,
 	return getAllRepositories(ctx, iRegistry)
 }
 
-func (a *AzureRegistryClient) GetImagesToScan(_ context.Context) (map[string]string, error) {
+func (a *AzureRegistryClient) GetImagesToScan(ctx context.Context) (map[string]string, error) {
 	registry, err := name.NewRegistry(a.Registry.LoginServer)
 	if err != nil {
 		return nil, err
@@ -40,7 +40,7 @@ func (a *AzureRegistryClient) GetImagesToScan(_ context.Context) (map[string]str
 	}
 	images := make(map[string]string, len(a.Registry.Repositories))
 	for _, repository := range a.Registry.Repositories {
-		tag, err := getImageLatestTag(repository, iRegistry)
+		tag, err := getImageLatestTag(ctx, repository, iRegistry, a.Options)
 		if err != nil {
 			return nil, err
 		}
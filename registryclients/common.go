@@ -2,13 +2,13 @@ package registryclients
 
 import (
 	"context"
-	"slices"
 	"sort"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/armosec/registryx/common"
 	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
@@ -18,62 +18,93 @@ const (
 )
 
 func getAllRepositories(ctx context.Context, registry interfaces.IRegistry) ([]string, error) {
-	var repos, pageRepos []string
-	var nextPage *common.PaginationOption
-	var err error
-
-	firstPage := common.MakePagination(registry.GetMaxPageSize())
-	catalogOpts := common.CatalogOption{}
-
-	for pageRepos, nextPage, err = registry.Catalog(ctx, firstPage, catalogOpts, authn.FromConfig(*registry.GetAuth())); ; pageRepos, nextPage, err = registry.Catalog(ctx, *nextPage, catalogOpts, authn.FromConfig(*registry.GetAuth())) {
+	var repos []string
+	for repo, err := range registries.Repositories(ctx, registry, common.CatalogOption{}) {
 		if err != nil {
 			return nil, err
 		}
-		if len(pageRepos) == 0 {
-			break
-		}
-		repos = append(repos, pageRepos...)
-
-		if nextPage == nil || nextPage.Cursor == "" {
-			break
-		}
+		repos = append(repos, repo)
 	}
 	return repos, nil
 }
 
-func getImageLatestTag(repo string, registry interfaces.IRegistry) (string, error) {
-	firstPage := common.MakePagination(1000)
+// getImageLatestTag returns the latest tag of repo, selected by the latest tag strategy of the repository when options configure one.
+// Only the tags matching the registry and repository tag filters of options are considered
+func getImageLatestTag(ctx context.Context, repo string, registry interfaces.IRegistry, options *common.RegistryOptions) (string, error) {
+	withAuth := remote.WithAuth(authn.Anonymous)
+	if auth := registry.GetAuth(); common.ValidateAuth(auth) == nil {
+		withAuth = remote.WithAuth(authn.FromConfig(*auth))
+	}
+	//the registry filter is applied by the registries created with options, it is applied again for the listed tags of other registries
+	opts := common.LatestTagsOption{Strategy: options.LatestTagStrategy(repo), Filter: options.RepositoryTagFilter(repo)}
+	matches := func(tag string) bool {
+		return !common.IsCosignTag(tag) && options.TagFilter().Matches(tag) && opts.Filter.Matches(tag)
+	}
+	if opts.Strategy != nil {
+		return getImageLatestTagWithStrategy(ctx, repo, registry, opts, matches, withAuth)
+	}
 	var tags []string
-	withAuth := remote.WithAuth(authn.FromConfig(*registry.GetAuth()))
-	if latestTags, err := registry.GetLatestTags(repo, 1, withAuth); err == nil {
-		for _, tag := range latestTags {
-			if strings.HasSuffix(tag, ".sig") {
+	if images, err := registry.GetLatestImages(ctx, repo, 1, opts, withAuth); err == nil {
+		for _, image := range images {
+			if len(image.Tags) == 0 || common.IsCosignTag(strings.Join(image.Tags, ",")) {
 				continue
 			}
-			tagsForDigest := strings.Split(tag, ",")
-			return tagsForDigest[0], nil
+			return image.Tags[0], nil
 		}
 	} else {
-		for tagsPage, nextPage, err := registry.List(repo, firstPage, withAuth); ; tagsPage, nextPage, err = registry.List(repo, *nextPage, withAuth) {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		for tag, err := range registries.Tags(ctx, registry, repo, withAuth) {
 			if err != nil {
 				return "", err
 			}
-
-			if slices.Contains(tagsPage, latestTag) {
-				return latestTag, nil
+			if !matches(tag) {
+				continue
 			}
-
-			tags = append(tags, tagsPage...)
-
-			if nextPage == nil {
-				break
+			if tag == latestTag {
+				return latestTag, nil
 			}
+			tags = append(tags, tag)
 		}
 		return getLatestTag(tags), nil
 	}
 	return "", nil
 }
 
+// getImageLatestTagWithStrategy returns the newest tag of the latest image ranked by opts.Strategy,
+// tag strategies fall back to ranking the listed tags accepted by matches when the images cannot be fetched
+func getImageLatestTagWithStrategy(ctx context.Context, repo string, registry interfaces.IRegistry, opts common.LatestTagsOption, matches func(tag string) bool, options ...remote.Option) (string, error) {
+	strategy := opts.Strategy
+	images, err := registry.GetLatestImages(ctx, repo, 1, opts, options...)
+	if err == nil {
+		for _, image := range images {
+			for _, tag := range image.Tags {
+				if !common.IsCosignTag(tag) {
+					return tag, nil
+				}
+			}
+		}
+		return "", nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil || !strategy.RanksTags() {
+		return "", err
+	}
+	newest := ""
+	for tag, err := range registries.Tags(ctx, registry, repo, options...) {
+		if err != nil {
+			return "", err
+		}
+		if !matches(tag) || !strategy.Accepts(tag) {
+			continue
+		}
+		if newest == "" || strategy.NewerTag(tag, newest) {
+			newest = tag
+		}
+	}
+	return newest, nil
+}
+
 func getLatestTag(tags []string) string {
 	var versions []*semver.Version
 	var nonSemverTags []string
@@ -1,10 +1,17 @@
 package registryclients
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_getLatestTag(t *testing.T) {
@@ -112,3 +119,87 @@ func Test_getLatestTag(t *testing.T) {
 		}
 	})
 }
+
+// tagsRegistry lists a single page of tags and fails to fetch images
+type tagsRegistry struct {
+	interfaces.IRegistry
+	tags   []string
+	images []common.TaggedImage
+	filter *common.TagFilter // the filter of the last GetLatestImages call
+}
+
+func (r *tagsRegistry) GetAuth() *authn.AuthConfig {
+	return &authn.AuthConfig{}
+}
+
+func (r *tagsRegistry) GetMaxPageSize() int {
+	return 100
+}
+
+func (r *tagsRegistry) ListWithContext(_ context.Context, _ string, _ common.PaginationOption, _ ...remote.Option) ([]string, *common.PaginationOption, error) {
+	return r.tags, nil, nil
+}
+
+func (r *tagsRegistry) GetLatestImages(_ context.Context, _ string, _ int, opts common.LatestTagsOption, _ ...remote.Option) ([]common.TaggedImage, error) {
+	r.filter = opts.Filter
+	if r.images == nil {
+		return nil, errors.New("unsupported media type")
+	}
+	return r.images, nil
+}
+
+func Test_getImageLatestTagWithStrategy(t *testing.T) {
+	ctx := context.Background()
+	registry := &tagsRegistry{tags: []string{"sha256-abc.sig", "1.2.0", "build-7", "1.10.0", "latest"}}
+	options := common.MakeRegistryOptions(false, false, false, "", "", "", common.Generic).
+		WithLatestTagStrategy(common.SemverStrategy())
+	build, err := common.BuildNumberStrategy(`^build-(\d+)$`)
+	assert.Nil(t, err)
+	options.WithRepositoryLatestTagStrategy("builds", build)
+
+	//images cannot be fetched, tag strategies rank the listed tags
+	tag, err := getImageLatestTag(ctx, "repo", registry, options)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.10.0", tag)
+	tag, err = getImageLatestTag(ctx, "builds", registry, options)
+	assert.Nil(t, err)
+	assert.Equal(t, "build-7", tag)
+
+	_, err = getImageLatestTag(ctx, "repo", registry, common.MakeRegistryOptions(false, false, false, "", "", "", common.Generic).WithLatestTagStrategy(common.PushedStrategy()))
+	assert.Error(t, err)
+
+	registry.images = []common.TaggedImage{{Tags: []string{"sha256-abc.sig", "1.2.0"}}}
+	tag, err = getImageLatestTag(ctx, "repo", registry, options)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.0", tag)
+}
+
+func Test_getImageLatestTagWithFilters(t *testing.T) {
+	ctx := context.Background()
+	registry := &tagsRegistry{tags: []string{"sha256-abc.sig", "1.2.0", "pr-12", "1.10.0-rc1", "latest"}}
+	releases := &common.TagFilter{SemverOnly: true}
+	options := common.MakeRegistryOptions(false, false, false, "", "", "", common.Generic).
+		WithTagFilter(&common.TagFilter{Exclude: []string{"pr-*"}}).
+		WithRepositoryTagFilter("releases", releases)
+
+	//images cannot be fetched, the filtered listed tags are ranked
+	tag, err := getImageLatestTag(ctx, "repo", registry, options)
+	assert.Nil(t, err)
+	assert.Equal(t, "latest", tag)
+	tag, err = getImageLatestTag(ctx, "releases", registry, options)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.10.0-rc1", tag)
+	assert.Same(t, releases, registry.filter)
+
+	options.WithLatestTagStrategy(common.SemverStrategy())
+	options.WithRepositoryTagFilter("releases", &common.TagFilter{Exclude: []string{"*-rc*"}})
+	tag, err = getImageLatestTag(ctx, "releases", registry, options)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.0", tag)
+
+	//the repository filter is passed to the registry
+	registry.images = []common.TaggedImage{{Tags: []string{"1.2.0"}}}
+	_, err = getImageLatestTag(ctx, "releases", registry, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"*-rc*"}, registry.filter.Exclude)
+}
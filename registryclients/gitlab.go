@@ -3,10 +3,12 @@ package registryclients
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/armosec/armoapi-go/armotypes"
@@ -20,8 +22,14 @@ import (
 type GitLabRegistryClient struct {
 	Registry *armotypes.GitlabImageRegistry
 	Options  *common.RegistryOptions
+	// Group scopes the discovery to the projects of a group and its subgroups (full path or ID),
+	// all the projects the token is a member of are discovered when empty
+	Group string
 }
 
+// gitLabPageSize is the largest page size of the GitLab API
+const gitLabPageSize = 100
+
 // GitLabProject represents a GitLab project from the API
 type gitLabProject struct {
 	ID                int    `json:"id"`
@@ -34,31 +42,68 @@ type gitLabRepository struct {
 	Path string `json:"path"`
 }
 
+// GitLabProjectError is the failure to list the container repositories of a project
+type GitLabProjectError struct {
+	ProjectID int
+	Project   string // path with namespace
+	Err       error
+}
+
+func (e *GitLabProjectError) Error() string {
+	return fmt.Sprintf("project %s (%d): %v", e.Project, e.ProjectID, e.Err)
+}
+
+func (e *GitLabProjectError) Unwrap() error {
+	return e.Err
+}
+
+// GitLabDiscoveryError reports the projects whose container repositories could not be listed
+type GitLabDiscoveryError struct {
+	Failures []GitLabProjectError
+}
+
+func (e *GitLabDiscoveryError) Error() string {
+	failures := make([]string, 0, len(e.Failures))
+	for i := range e.Failures {
+		failures = append(failures, e.Failures[i].Error())
+	}
+	return fmt.Sprintf("failed to list the container repositories of %d projects: %s", len(e.Failures), strings.Join(failures, "; "))
+}
+
+// GetAllRepositories returns the container repositories of the projects of the token (or of Group).
+// When some projects cannot be listed the repositories of the other projects are returned with a *GitLabDiscoveryError
 func (g *GitLabRegistryClient) GetAllRepositories(ctx context.Context) ([]string, error) {
 	return g.getRepositoriesFromGitLabAPI(ctx)
 }
 
 func (g *GitLabRegistryClient) getRepositoriesFromGitLabAPI(ctx context.Context) ([]string, error) {
-	baseURL := g.getGitLabAPIBaseURL()
+	return g.discoverRepositories(ctx, &http.Client{}, g.getGitLabAPIBaseURL())
+}
 
-	projects, err := g.getUserProjects(ctx, baseURL)
+func (g *GitLabRegistryClient) discoverRepositories(ctx context.Context, httpClient *http.Client, baseURL string) ([]string, error) {
+	projects, err := g.getProjects(ctx, httpClient, baseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user projects: %w", err)
+		return nil, fmt.Errorf("failed to get projects: %w", err)
 	}
 
-	var allRepos []string
-	httpClient := &http.Client{}
-
+	allRepos := []string{}
+	discoveryErr := &GitLabDiscoveryError{}
 	for _, project := range projects {
 		repos, err := g.getProjectRepositories(ctx, httpClient, baseURL, project.ID)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			discoveryErr.Failures = append(discoveryErr.Failures, GitLabProjectError{ProjectID: project.ID, Project: project.PathWithNamespace, Err: err})
 			continue
 		}
 		for _, repo := range repos {
 			allRepos = append(allRepos, repo.Path)
 		}
 	}
-
+	if len(discoveryErr.Failures) > 0 {
+		return allRepos, discoveryErr
+	}
 	return allRepos, nil
 }
 
@@ -99,88 +144,109 @@ func hostLooksLikeGitLab(host string) bool {
 	return strings.Contains(host, "gitlab")
 }
 
-func (g *GitLabRegistryClient) getUserProjects(ctx context.Context, baseURL string) ([]gitLabProject, error) {
-	var allProjects []gitLabProject
-	page := 1
-	perPage := 100
-	httpClient := &http.Client{}
+// getProjects lists the projects of Group and its subgroups, or the projects the token is a member of,
+// with at least the developer role required to read their container registry
+func (g *GitLabRegistryClient) getProjects(ctx context.Context, httpClient *http.Client, baseURL string) ([]gitLabProject, error) {
+	query := url.Values{}
+	query.Set("per_page", strconv.Itoa(gitLabPageSize))
+	query.Set("min_access_level", "30")
+	uri := baseURL + "/projects"
+	if g.Group != "" {
+		uri = fmt.Sprintf("%s/groups/%s/projects", baseURL, url.PathEscape(g.Group))
+		query.Set("include_subgroups", "true")
+	} else {
+		query.Set("membership", "true")
+		//keyset pagination is not limited to the first 10,000 projects
+		query.Set("pagination", "keyset")
+		query.Set("order_by", "id")
+		query.Set("sort", "asc")
+	}
+	return getAllPages[gitLabProject](ctx, httpClient, uri+"?"+query.Encode(), g.Registry.AccessToken)
+}
 
-	for {
-		url := fmt.Sprintf("%s/projects?page=%d&per_page=%d&min_access_level=30&membership=true",
-			baseURL, page, perPage)
+func (g *GitLabRegistryClient) getProjectRepositories(ctx context.Context, httpClient *http.Client, baseURL string, projectID int) ([]gitLabRepository, error) {
+	uri := fmt.Sprintf("%s/projects/%d/registry/repositories?per_page=%d", baseURL, projectID, gitLabPageSize)
+	repos, err := getAllPages[gitLabRepository](ctx, httpClient, uri, g.Registry.AccessToken)
+	var apiErr *gitLabAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		//the container registry of the project is disabled
+		return []gitLabRepository{}, nil
+	}
+	return repos, err
+}
 
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
+// gitLabAPIError is an unexpected status of the GitLab API
+type gitLabAPIError struct {
+	StatusCode int
+	Body       string
+}
 
-		req.Header.Set("PRIVATE-TOKEN", g.Registry.AccessToken)
+func (e *gitLabAPIError) Error() string {
+	return fmt.Sprintf("GitLab API error (status %d): %s", e.StatusCode, e.Body)
+}
 
-		resp, err := httpClient.Do(req)
+// getAllPages gets all the pages of a GitLab API listing, following the next link of keyset pagination
+// or the X-Next-Page header of offset pagination
+func getAllPages[T any](ctx context.Context, httpClient *http.Client, uri string, token string) ([]T, error) {
+	all := []T{}
+	for uri != "" {
+		var page []T
+		next, err := getPage(ctx, httpClient, uri, token, &page)
 		if err != nil {
 			return nil, err
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
-		}
-
-		var projects []gitLabProject
-		if err := json.NewDecoder(resp.Body).Decode(&projects); err != nil {
-			return nil, fmt.Errorf("failed to decode projects: %w", err)
-		}
-
-		if len(projects) == 0 {
-			break
-		}
-
-		allProjects = append(allProjects, projects...)
-
-		if len(projects) < perPage {
-			break
-		}
-		page++
+		all = append(all, page...)
+		uri = next
 	}
-
-	return allProjects, nil
+	return all, nil
 }
 
-func (g *GitLabRegistryClient) getProjectRepositories(ctx context.Context, httpClient *http.Client, baseURL string, projectID int) ([]gitLabRepository, error) {
-	url := fmt.Sprintf("%s/projects/%d/registry/repositories", baseURL, projectID)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// getPage decodes a page of a GitLab API listing into v and returns the URL of the next page, empty on the last page
+func getPage(ctx context.Context, httpClient *http.Client, uri string, token string, v any) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-
-	req.Header.Set("PRIVATE-TOKEN", g.Registry.AccessToken)
-
+	req.Header.Set("PRIVATE-TOKEN", token)
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNotFound {
-		return []gitLabRepository{}, nil
-	}
-
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GitLab API error: %d - %s", resp.StatusCode, string(body))
+		return "", &gitLabAPIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
-
-	var repos []gitLabRepository
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-		return nil, err
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", req.URL.Path, err)
+	}
+	if next := nextLink(resp.Header.Get("Link")); next != "" {
+		return next, nil
 	}
+	nextPage := resp.Header.Get("X-Next-Page")
+	if nextPage == "" {
+		return "", nil
+	}
+	nextURL := *req.URL
+	query := nextURL.Query()
+	query.Set("page", nextPage)
+	nextURL.RawQuery = query.Encode()
+	return nextURL.String(), nil
+}
 
-	return repos, nil
+// nextLink returns the rel="next" URL of a Link header
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, found := strings.Cut(strings.TrimSpace(link), ";")
+		if !found || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(target), "<"), ">")
+	}
+	return ""
 }
 
-func (g *GitLabRegistryClient) GetImagesToScan(_ context.Context) (map[string]string, error) {
+func (g *GitLabRegistryClient) GetImagesToScan(ctx context.Context) (map[string]string, error) {
 	registry, err := name.NewRegistry(g.Registry.RegistryURL)
 	if err != nil {
 		return nil, err
@@ -192,7 +258,7 @@ func (g *GitLabRegistryClient) GetImagesToScan(_ context.Context) (map[string]st
 
 	images := make(map[string]string, len(g.Registry.Repositories))
 	for _, repository := range g.Registry.Repositories {
-		tag, err := getImageLatestTag(repository, iRegistry)
+		tag, err := getImageLatestTag(ctx, repository, iRegistry, g.Options)
 		if err != nil {
 			return nil, err
 		}
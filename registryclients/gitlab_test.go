@@ -1,10 +1,16 @@
 package registryclients
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/armosec/armoapi-go/armotypes"
 	"github.com/armosec/registryx/common"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGitLabRegistryClient_getGitLabAPIBaseURL(t *testing.T) {
@@ -279,3 +285,90 @@ func TestGitLabRegistryClient_getGitLabAPIBaseURL(t *testing.T) {
 		})
 	}
 }
+
+// gitLabAPIHandler serves the projects of the token with keyset pagination, the projects of my-group/sub,
+// and the container repositories of the projects with offset pagination
+func gitLabAPIHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "glpat-token", r.Header.Get("PRIVATE-TOKEN"))
+		query := r.URL.Query()
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects":
+			assert.Equal(t, "keyset", query.Get("pagination"))
+			assert.Equal(t, "true", query.Get("membership"))
+			if query.Get("id_after") == "" {
+				w.Header().Set("Link", fmt.Sprintf(`<http://%s/api/v4/projects?id_after=1&membership=true&min_access_level=30&order_by=id&pagination=keyset&per_page=100&sort=asc>; rel="next"`, r.Host))
+				w.Write([]byte(`[{"id": 1, "path_with_namespace": "my-group/app"}]`))
+				return
+			}
+			assert.Equal(t, "1", query.Get("id_after"))
+			w.Write([]byte(`[{"id": 2, "path_with_namespace": "my-group/broken"}, {"id": 3, "path_with_namespace": "my-group/docs"}]`))
+		case "/api/v4/groups/my-group%2Fsub/projects":
+			assert.Equal(t, "true", query.Get("include_subgroups"))
+			w.Write([]byte(`[{"id": 1, "path_with_namespace": "my-group/sub/app"}]`))
+		case "/api/v4/projects/1/registry/repositories":
+			if query.Get("page") == "" {
+				w.Header().Set("X-Next-Page", "2")
+				w.Write([]byte(`[{"id": 10, "path": "my-group/app"}]`))
+				return
+			}
+			assert.Equal(t, "2", query.Get("page"))
+			w.Header().Set("X-Next-Page", "")
+			w.Write([]byte(`[{"id": 11, "path": "my-group/app/worker"}]`))
+		case "/api/v4/projects/2/registry/repositories":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "403 Forbidden"}`))
+		case "/api/v4/projects/3/registry/repositories":
+			//container registry disabled
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestGitLabDiscoverRepositories(t *testing.T) {
+	server := httptest.NewServer(gitLabAPIHandler(t))
+	defer server.Close()
+	client := &GitLabRegistryClient{Registry: &armotypes.GitlabImageRegistry{AccessToken: "glpat-token"}, Options: &common.RegistryOptions{}}
+
+	repos, err := client.discoverRepositories(context.Background(), server.Client(), server.URL+"/api/v4")
+	//the repositories of the other projects are returned with the failures
+	assert.Equal(t, []string{"my-group/app", "my-group/app/worker"}, repos)
+	var discoveryErr *GitLabDiscoveryError
+	assert.True(t, errors.As(err, &discoveryErr))
+	assert.Len(t, discoveryErr.Failures, 1)
+	assert.Equal(t, 2, discoveryErr.Failures[0].ProjectID)
+	assert.Equal(t, "my-group/broken", discoveryErr.Failures[0].Project)
+	var apiErr *gitLabAPIError
+	assert.True(t, errors.As(discoveryErr.Failures[0].Err, &apiErr))
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	assert.Contains(t, err.Error(), "my-group/broken")
+
+	client.Group = "my-group/sub"
+	repos, err = client.discoverRepositories(context.Background(), server.Client(), server.URL+"/api/v4")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"my-group/app", "my-group/app/worker"}, repos)
+}
+
+func TestGitLabDiscoverRepositoriesProjectsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	client := &GitLabRegistryClient{Registry: &armotypes.GitlabImageRegistry{AccessToken: "expired"}, Options: &common.RegistryOptions{}}
+
+	repos, err := client.discoverRepositories(context.Background(), server.Client(), server.URL+"/api/v4")
+	assert.Nil(t, repos)
+	var apiErr *gitLabAPIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+func TestNextLink(t *testing.T) {
+	assert.Equal(t, "https://gitlab.example.com/api/v4/projects?id_after=42", nextLink(`<https://gitlab.example.com/api/v4/projects?id_after=42>; rel="next"`))
+	assert.Equal(t, "https://gitlab.example.com/api/v4/projects?page=3", nextLink(`<https://gitlab.example.com/api/v4/projects?page=1>; rel="first", <https://gitlab.example.com/api/v4/projects?page=3>; rel="next"`))
+	assert.Empty(t, nextLink(`<https://gitlab.example.com/api/v4/projects?page=1>; rel="first"`))
+	assert.Empty(t, nextLink(""))
+}
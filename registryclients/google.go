@@ -65,7 +65,7 @@ func (g *GoogleArtifactRegistryClient) GetAllRepositories(ctx context.Context) (
 	return getAllRepositories(ctx, iRegistry)
 }
 
-func (g *GoogleArtifactRegistryClient) GetImagesToScan(_ context.Context) (map[string]string, error) {
+func (g *GoogleArtifactRegistryClient) GetImagesToScan(ctx context.Context) (map[string]string, error) {
 	registry, err := name.NewRegistry(g.Registry.RegistryURI)
 	if err != nil {
 		return nil, err
@@ -81,7 +81,7 @@ func (g *GoogleArtifactRegistryClient) GetImagesToScan(_ context.Context) (map[s
 
 	images := make(map[string]string, len(g.Registry.Repositories))
 	for _, repository := range g.Registry.Repositories {
-		tag, err := getImageLatestTag(repository, iRegistry)
+		tag, err := getImageLatestTag(ctx, repository, iRegistry, g.Options)
 		if err != nil {
 			return nil, err
 		}
@@ -3,45 +3,53 @@ package registryclients
 import (
 	"context"
 	"fmt"
+
 	"github.com/armosec/armoapi-go/armotypes"
 	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
 	"github.com/armosec/registryx/registries/harbor"
 	dockerregistry "github.com/docker/docker/api/types/registry"
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 )
 
 type HarborRegistryClient struct {
 	Registry *armotypes.HarborImageRegistry
 	Options  *common.RegistryOptions
+	// AuthMethod is the kind of the Registry credentials: a user password (default), a robot account secret,
+	// an OIDC CLI secret, or anonymous access to the public projects
+	AuthMethod harbor.AuthMethod
 }
 
-func (h *HarborRegistryClient) GetAllRepositories(ctx context.Context) ([]string, error) {
+func (h *HarborRegistryClient) newRegistry() (interfaces.IRegistry, error) {
 	registry, err := name.NewRegistry(h.Registry.InstanceURL)
 	if err != nil {
 		return nil, err
 	}
-	iRegistry, err := harbor.NewHarborRegistry(&authn.AuthConfig{Username: h.Registry.Username, Password: h.Registry.Password}, &registry, h.Options)
+	auth, err := harbor.MakeAuth(h.AuthMethod, h.Registry.Username, h.Registry.Password)
 	if err != nil {
 		return nil, err
 	}
-
-	return getAllRepositories(ctx, iRegistry)
+	return harbor.NewHarborRegistry(auth, &registry, h.Options)
 }
 
-func (h *HarborRegistryClient) GetImagesToScan(_ context.Context) (map[string]string, error) {
-	registry, err := name.NewRegistry(h.Registry.InstanceURL)
+func (h *HarborRegistryClient) GetAllRepositories(ctx context.Context) ([]string, error) {
+	iRegistry, err := h.newRegistry()
 	if err != nil {
 		return nil, err
 	}
-	iRegistry, err := harbor.NewHarborRegistry(&authn.AuthConfig{Username: h.Registry.Username, Password: h.Registry.Password}, &registry, h.Options)
+
+	return getAllRepositories(ctx, iRegistry)
+}
+
+func (h *HarborRegistryClient) GetImagesToScan(ctx context.Context) (map[string]string, error) {
+	iRegistry, err := h.newRegistry()
 	if err != nil {
 		return nil, err
 	}
 
 	images := make(map[string]string, len(h.Registry.Repositories))
 	for _, repository := range h.Registry.Repositories {
-		tag, err := getImageLatestTag(repository, iRegistry)
+		tag, err := getImageLatestTag(ctx, repository, iRegistry, h.Options)
 		if err != nil {
 			return nil, err
 		}
@@ -52,9 +60,17 @@ func (h *HarborRegistryClient) GetImagesToScan(_ context.Context) (map[string]st
 	return images, nil
 }
 
+// GetDockerAuth returns the credentials of the auth method, empty credentials for anonymous access
 func (h *HarborRegistryClient) GetDockerAuth() (*dockerregistry.AuthConfig, error) {
+	auth, err := harbor.MakeAuth(h.AuthMethod, h.Registry.Username, h.Registry.Password)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil {
+		return &dockerregistry.AuthConfig{}, nil
+	}
 	return &dockerregistry.AuthConfig{
-		Username: h.Registry.Username,
-		Password: h.Registry.Password,
+		Username: auth.Username,
+		Password: auth.Password,
 	}, nil
 }
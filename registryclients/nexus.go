@@ -31,7 +31,7 @@ func (n *NexusRegistryClient) GetAllRepositories(ctx context.Context) ([]string,
 	return getAllRepositories(ctx, iRegistry)
 }
 
-func (n *NexusRegistryClient) GetImagesToScan(_ context.Context) (map[string]string, error) {
+func (n *NexusRegistryClient) GetImagesToScan(ctx context.Context) (map[string]string, error) {
 	registry, err := name.NewRegistry(n.Registry.RegistryURL)
 	if err != nil {
 		return nil, err
@@ -43,7 +43,7 @@ func (n *NexusRegistryClient) GetImagesToScan(_ context.Context) (map[string]str
 
 	images := make(map[string]string, len(n.Registry.Repositories))
 	for _, repository := range n.Registry.Repositories {
-		tag, err := getImageLatestTag(repository, iRegistry)
+		tag, err := getImageLatestTag(ctx, repository, iRegistry, n.Options)
 		if err != nil {
 			return nil, err
 		}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/armosec/armoapi-go/armotypes"
 	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
 	"github.com/armosec/registryx/registries/quay"
 	dockerregistry "github.com/docker/docker/api/types/registry"
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -14,33 +15,36 @@ import (
 type QuayRegistryClient struct {
 	Registry *armotypes.QuayImageRegistry
 	Options  *common.RegistryOptions
+	// OAuthToken is an OAuth application token authorizing the quay API (/api/v1) endpoints
+	OAuthToken string
 }
 
-func (q *QuayRegistryClient) GetAllRepositories(ctx context.Context) ([]string, error) {
+func (q *QuayRegistryClient) newRegistry() (interfaces.IRegistry, error) {
 	registry, err := name.NewRegistry(q.Registry.ContainerRegistryName)
 	if err != nil {
 		return nil, err
 	}
-	iRegistry, err := quay.NewQuayIORegistry(&authn.AuthConfig{Username: q.Registry.RobotAccountName, Password: q.Registry.RobotAccountToken}, &registry, q.Options)
+	auth := &authn.AuthConfig{Username: q.Registry.RobotAccountName, Password: q.Registry.RobotAccountToken, IdentityToken: q.OAuthToken}
+	return quay.NewQuayIORegistry(auth, &registry, q.Options)
+}
+
+func (q *QuayRegistryClient) GetAllRepositories(ctx context.Context) ([]string, error) {
+	iRegistry, err := q.newRegistry()
 	if err != nil {
 		return nil, err
 	}
 	return getAllRepositories(ctx, iRegistry)
 }
 
-func (q *QuayRegistryClient) GetImagesToScan(_ context.Context) (map[string]string, error) {
-	registry, err := name.NewRegistry(q.Registry.ContainerRegistryName)
-	if err != nil {
-		return nil, err
-	}
-	iRegistry, err := quay.NewQuayIORegistry(&authn.AuthConfig{Username: q.Registry.RobotAccountName, Password: q.Registry.RobotAccountToken}, &registry, q.Options)
+func (q *QuayRegistryClient) GetImagesToScan(ctx context.Context) (map[string]string, error) {
+	iRegistry, err := q.newRegistry()
 	if err != nil {
 		return nil, err
 	}
 
 	images := make(map[string]string, len(q.Registry.Repositories))
 	for _, repository := range q.Registry.Repositories {
-		tag, err := getImageLatestTag(repository, iRegistry)
+		tag, err := getImageLatestTag(ctx, repository, iRegistry, q.Options)
 		if err != nil {
 			return nil, err
 		}
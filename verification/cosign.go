@@ -0,0 +1,345 @@
+package verification
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const (
+	CosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	CosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	CosignChainAnnotation       = "dev.sigstore.cosign/chain"
+
+	cosignSignatureType = "cosign container image signature"
+	maxPayloadSize      = 1 << 20
+)
+
+var (
+	// fulcioIssuerOID is the deprecated Fulcio OIDC issuer extension, its value is the raw issuer
+	fulcioIssuerOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	// fulcioIssuerV2OID is the Fulcio OIDC issuer extension, its value is a DER encoded UTF8String
+	fulcioIssuerV2OID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// CosignVerifier verifies cosign simple-signing signatures offline, without contacting Rekor or Fulcio.
+// A signature is trusted if it verifies against one of PublicKeys, or against a certificate embedded in the signature
+// that chains to Roots and matches Identities and Issuers.
+// There is no transparency log timestamp, so certificates must be valid at the current time: short lived keyless
+// certificates are rejected once expired
+type CosignVerifier struct {
+	PublicKeys    []crypto.PublicKey
+	Roots         *x509.CertPool
+	Intermediates *x509.CertPool
+	// Identities are the trusted email or URI SANs or subjects of the certificates, any identity is trusted when empty
+	Identities []string
+	// Issuers are the trusted OIDC issuers of Fulcio certificates, any issuer is trusted when empty
+	Issuers []string
+	// Now returns the current time, used to verify the certificate chain (defaults to time.Now)
+	Now func() time.Time
+}
+
+func NewCosignVerifier(publicKeys ...crypto.PublicKey) *CosignVerifier {
+	return &CosignVerifier{PublicKeys: publicKeys}
+}
+
+// ParsePublicKeys parses the PEM encoded public keys and certificates in data, certificates are trusted by their public key
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, cert.PublicKey)
+		default:
+			return nil, fmt.Errorf("unsupported PEM block %s", block.Type)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no public keys found")
+	}
+	return keys, nil
+}
+
+// simpleSigningPayload is the cosign flavour of the containers/image simple signing format
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+func (v *CosignVerifier) Verify(ctx context.Context, registry interfaces.IRegistry, repoName string, digest string, options ...remote.Option) (*Verdict, error) {
+	referrers, err := registry.Referrers(ctx, repoName, digest, options...)
+	if err != nil {
+		return nil, err
+	}
+	var signatures []SignatureVerdict
+	for _, referrer := range referrers {
+		if referrer.ArtifactType != common.CosignSignatureArtifactType {
+			continue
+		}
+		artifact, err := fetchArtifact(ctx, registry, repoName, referrer.Digest, options...)
+		if err != nil {
+			return nil, err
+		}
+		verdicts, err := v.verifyArtifact(artifact, referrer.Digest, digest)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, verdicts...)
+	}
+	return newVerdict(repoName, digest, signatures), nil
+}
+
+// verifyArtifact verifies every signature layer of a cosign signature manifest
+func (v *CosignVerifier) verifyArtifact(artifact v1.Image, artifactDigest, digest string) ([]SignatureVerdict, error) {
+	manifest, err := artifact.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	var verdicts []SignatureVerdict
+	for _, descriptor := range manifest.Layers {
+		if descriptor.MediaType != common.CosignSimpleSigningMediaType {
+			continue
+		}
+		verdict := SignatureVerdict{Format: Cosign, Digest: artifactDigest}
+		layer, err := artifact.LayerByDigest(descriptor.Digest)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := readPayload(layer)
+		if err != nil {
+			return nil, err
+		}
+		verdict.Signer, verdict.Reason = v.verifySignature(payload, descriptor.Annotations, digest)
+		verdict.Verified = verdict.Reason == ""
+		verdicts = append(verdicts, verdict)
+	}
+	return verdicts, nil
+}
+
+// verifySignature returns the signer of a verified payload or the reason the verification failed
+func (v *CosignVerifier) verifySignature(payload []byte, annotations map[string]string, digest string) (signer string, reason string) {
+	signature, err := base64.StdEncoding.DecodeString(annotations[CosignSignatureAnnotation])
+	if err != nil || len(signature) == 0 {
+		return "", "missing or malformed signature annotation"
+	}
+	signed := simpleSigningPayload{}
+	if err := json.Unmarshal(payload, &signed); err != nil {
+		return "", fmt.Sprintf("malformed payload: %v", err)
+	}
+	if !strings.EqualFold(signed.Critical.Type, cosignSignatureType) {
+		return "", fmt.Sprintf("unexpected payload type %q", signed.Critical.Type)
+	}
+	if signed.Critical.Image.DockerManifestDigest != digest {
+		return "", fmt.Sprintf("payload is signed for %s", signed.Critical.Image.DockerManifestDigest)
+	}
+	for _, key := range v.PublicKeys {
+		if verifySignature(key, payload, signature) == nil {
+			return keyFingerprint(key), ""
+		}
+	}
+	if pemCert, ok := annotations[CosignCertificateAnnotation]; ok && v.Roots != nil {
+		cert, err := v.verifyCertificate(pemCert, annotations[CosignChainAnnotation])
+		if err != nil {
+			return "", fmt.Sprintf("untrusted certificate: %v", err)
+		}
+		if err := verifySignature(cert.PublicKey, payload, signature); err != nil {
+			return "", fmt.Sprintf("signature does not match the certificate: %v", err)
+		}
+		if reason := v.verifyIdentity(cert); reason != "" {
+			return certificateSigner(cert), reason
+		}
+		return certificateSigner(cert), ""
+	}
+	return "", "signature does not match any trusted key"
+}
+
+// verifyCertificate verifies the signing certificate chains to the trusted roots.
+// Without a transparency log timestamp the chain is checked at the current time
+func (v *CosignVerifier) verifyCertificate(pemCert, pemChain string) (*x509.Certificate, error) {
+	certs, err := parseCertificates([]byte(pemCert))
+	if err != nil {
+		return nil, err
+	}
+	intermediates := x509.NewCertPool()
+	if v.Intermediates != nil {
+		intermediates = v.Intermediates.Clone()
+	}
+	if pemChain != "" {
+		chain, err := parseCertificates([]byte(pemChain))
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range chain {
+			intermediates.AddCert(cert)
+		}
+	}
+	cert := certs[0]
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         v.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	return cert, err
+}
+
+// verifyIdentity returns the reason the certificate identity or issuer is not trusted, empty if it is
+func (v *CosignVerifier) verifyIdentity(cert *x509.Certificate) string {
+	if len(v.Identities) > 0 && !slices.ContainsFunc(certificateIdentities(cert), func(identity string) bool {
+		return slices.Contains(v.Identities, identity)
+	}) {
+		return fmt.Sprintf("signer %s is not a trusted identity", certificateSigner(cert))
+	}
+	if len(v.Issuers) > 0 {
+		issuer := certificateIssuer(cert)
+		if !slices.Contains(v.Issuers, issuer) {
+			return fmt.Sprintf("issuer %q is not a trusted issuer", issuer)
+		}
+	}
+	return ""
+}
+
+func (v *CosignVerifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+func readPayload(layer v1.Layer) ([]byte, error) {
+	reader, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	payload, err := io.ReadAll(io.LimitReader(reader, maxPayloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > maxPayloadSize {
+		return nil, fmt.Errorf("signature payload exceeds %d bytes", maxPayloadSize)
+	}
+	return payload, nil
+}
+
+// verifySignature verifies a signature of payload made with SHA-256 (ECDSA, RSA PKCS#1 v1.5 or PSS) or ed25519
+func verifySignature(key crypto.PublicKey, payload, signature []byte) error {
+	hash := sha256.Sum256(payload)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hash[:], signature) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); err != nil {
+			return rsa.VerifyPSS(key, crypto.SHA256, hash[:], signature, nil)
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, signature) {
+			return errors.New("invalid ed25519 signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type %T", key)
+}
+
+// keyFingerprint identifies a key by the SHA-256 of its DER encoding
+func keyFingerprint(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// certificateIdentities returns the email and URI SANs and the subject of a certificate
+func certificateIdentities(cert *x509.Certificate) []string {
+	identities := slices.Clone(cert.EmailAddresses)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return append(identities, cert.Subject.String())
+}
+
+// certificateIssuer returns the OIDC issuer of a Fulcio certificate, empty if there is none
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, extension := range cert.Extensions {
+		switch {
+		case extension.Id.Equal(fulcioIssuerV2OID):
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(extension.Value, &issuer, "utf8"); err == nil {
+				return issuer
+			}
+		case extension.Id.Equal(fulcioIssuerOID):
+			return string(extension.Value)
+		}
+	}
+	return ""
+}
+
+// certificateSigner identifies a certificate by its email or URI SAN, falling back to the subject
+func certificateSigner(cert *x509.Certificate) string {
+	if len(cert.EmailAddresses) > 0 {
+		return cert.EmailAddresses[0]
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	return cert.Subject.String()
+}
@@ -0,0 +1,209 @@
+package verification
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries"
+	"github.com/armosec/registryx/registries/defaultregistry"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/stretchr/testify/assert"
+)
+
+func newInMemoryRegistry(t *testing.T) (interfaces.IRegistry, *httptest.Server) {
+	server := httptest.NewServer(registry.New())
+	reg, err := name.NewRegistry(strings.TrimPrefix(server.URL, "http://"), name.Insecure)
+	assert.Nil(t, err)
+	iRegistry, err := defaultregistry.NewRegistry(nil, &reg, common.MakeRegistryOptions(false, true, false, "", "", "", common.Generic))
+	assert.Nil(t, err)
+	return iRegistry, server
+}
+
+// pushImage pushes a random image to repo:tag and returns its digest
+func pushImage(t *testing.T, repo *name.Repository, tag string) string {
+	image, err := random.Image(64, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag(tag), image))
+	digest, err := image.Digest()
+	assert.Nil(t, err)
+	return digest.String()
+}
+
+// pushCosignSignature signs digest with key and pushes the signature to its cosign tag
+func pushCosignSignature(t *testing.T, repo *name.Repository, key *ecdsa.PrivateKey, digest, signedDigest string) {
+	pushCosignSignatureWithAnnotations(t, repo, key, digest, signedDigest, map[string]string{})
+}
+
+// pushCosignSignatureWithAnnotations signs digest with key and pushes the signature with extra layer annotations
+func pushCosignSignatureWithAnnotations(t *testing.T, repo *name.Repository, key *ecdsa.PrivateKey, digest, signedDigest string, annotations map[string]string) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`, repo.String(), signedDigest))
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	assert.Nil(t, err)
+	annotations[CosignSignatureAnnotation] = base64.StdEncoding.EncodeToString(signature)
+	image, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, common.CosignSimpleSigningMediaType),
+		Annotations: annotations,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Tag(common.CosignTag(digest, common.CosignSignatureTagSuffix)), image))
+}
+
+func TestCosignVerifier(t *testing.T) {
+	iRegistry, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", iRegistry.GetRegistry())
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	keys, err := ParsePublicKeys(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.Nil(t, err)
+	verifier := NewCosignVerifier(keys...)
+
+	signed := pushImage(t, repo, "signed")
+	pushCosignSignature(t, repo, key, signed, signed)
+	unsigned := pushImage(t, repo, "unsigned")
+	otherSigner := pushImage(t, repo, "other-signer")
+	pushCosignSignature(t, repo, otherKey, otherSigner, otherSigner)
+	wrongDigest := pushImage(t, repo, "wrong-digest")
+	pushCosignSignature(t, repo, key, wrongDigest, signed)
+
+	verdict, err := verifier.Verify(ctx, iRegistry, "my-repo", signed)
+	assert.Nil(t, err)
+	assert.True(t, verdict.Verified())
+	assert.Len(t, verdict.Signatures, 1)
+	assert.Equal(t, Cosign, verdict.Signatures[0].Format)
+	assert.Equal(t, keyFingerprint(&key.PublicKey), verdict.Signatures[0].Signer)
+
+	verdicts := VerifyAll(ctx, iRegistry, verifier, []registries.ResolveRequest{
+		{Repository: "my-repo", Reference: "signed"},
+		{Repository: "my-repo", Reference: "unsigned"},
+		{Repository: "my-repo", Reference: "other-signer"},
+		{Repository: "my-repo", Reference: "wrong-digest"},
+		{Repository: "my-repo", Reference: "missing"},
+	}, 2)
+	assert.Len(t, verdicts, 5)
+	assert.Equal(t, Verified, verdicts[0].Status)
+	assert.Equal(t, "signed", verdicts[0].Reference)
+	assert.Equal(t, signed, verdicts[0].Digest)
+	assert.Equal(t, Unsigned, verdicts[1].Status)
+	assert.Equal(t, unsigned, verdicts[1].Digest)
+	assert.Equal(t, Invalid, verdicts[2].Status)
+	assert.Equal(t, "signature does not match any trusted key", verdicts[2].Signatures[0].Reason)
+	assert.Equal(t, Invalid, verdicts[3].Status)
+	assert.Contains(t, verdicts[3].Signatures[0].Reason, "payload is signed for")
+	assert.Equal(t, Failed, verdicts[4].Status)
+	assert.Error(t, verdicts[4].Err)
+
+	_, err = ParsePublicKeys([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+// newFulcioCertificate issues a code signing certificate for email by the OIDC issuer, as Fulcio does
+func newFulcioCertificate(t *testing.T, email, issuer string, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	issuerValue, err := asn1.MarshalWithParams(issuer, "utf8")
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(time.Now().UnixNano()),
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		EmailAddresses:  []string{email},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: fulcioIssuerV2OID, Value: issuerValue}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return cert
+}
+
+func TestCosignCertificateVerifier(t *testing.T) {
+	iRegistry, server := newInMemoryRegistry(t)
+	defer server.Close()
+	repo, err := common.MakeRepoWithRegistry("my-repo", iRegistry.GetRegistry())
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ca := newCertificateValidity(t, pkix.Name{CommonName: "ca"}, caKey, nil, nil, time.Now().Add(-24*time.Hour), time.Now().Add(24*time.Hour))
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	pemCertificate := func(cert *x509.Certificate) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+	pushSigned := func(tag string, cert *x509.Certificate) string {
+		digest := pushImage(t, repo, tag)
+		pushCosignSignatureWithAnnotations(t, repo, key, digest, digest, map[string]string{CosignCertificateAnnotation: pemCertificate(cert)})
+		return digest
+	}
+
+	signed := pushSigned("signed", newFulcioCertificate(t, "dev@example.com", "https://accounts.example.com", key, ca, caKey))
+	otherIdentity := pushSigned("other-identity", newFulcioCertificate(t, "eve@example.com", "https://accounts.example.com", key, ca, caKey))
+	otherIssuer := pushSigned("other-issuer", newFulcioCertificate(t, "dev@example.com", "https://evil.example.com", key, ca, caKey))
+	//expired certificates are not trusted without a transparency log timestamp
+	expired := pushSigned("expired", newCertificateValidity(t, pkix.Name{CommonName: "dev"}, key, ca, caKey, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour)))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	verifier := &CosignVerifier{Roots: roots, Identities: []string{"dev@example.com", "CN=dev"}, Issuers: []string{"https://accounts.example.com"}}
+
+	verdict, err := verifier.Verify(ctx, iRegistry, "my-repo", signed)
+	assert.Nil(t, err)
+	assert.True(t, verdict.Verified())
+	assert.Equal(t, "dev@example.com", verdict.Signatures[0].Signer)
+
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", otherIdentity)
+	assert.Nil(t, err)
+	assert.False(t, verdict.Verified())
+	assert.Equal(t, "signer eve@example.com is not a trusted identity", verdict.Signatures[0].Reason)
+
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", otherIssuer)
+	assert.Nil(t, err)
+	assert.False(t, verdict.Verified())
+	assert.Equal(t, `issuer "https://evil.example.com" is not a trusted issuer`, verdict.Signatures[0].Reason)
+
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", expired)
+	assert.Nil(t, err)
+	assert.False(t, verdict.Verified())
+	assert.Contains(t, verdict.Signatures[0].Reason, "untrusted certificate")
+
+	//the certificate was valid when it was issued
+	verifier.Now = func() time.Time { return time.Now().Add(-90 * time.Minute) }
+	verifier.Issuers = nil
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", expired)
+	assert.Nil(t, err)
+	assert.True(t, verdict.Verified())
+	assert.Equal(t, "CN=dev", verdict.Signatures[0].Signer)
+}
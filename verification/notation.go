@@ -0,0 +1,419 @@
+package verification
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/fxamacker/cbor/v2"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	NotationJWSMediaType  types.MediaType = "application/jose+json"
+	NotationCOSEMediaType types.MediaType = "application/cose"
+
+	notationSigningSchemeHeader        = "io.cncf.notary.signingScheme"
+	notationSigningTimeHeader          = "io.cncf.notary.signingTime"
+	notationAuthenticSigningTimeHeader = "io.cncf.notary.authenticSigningTime"
+	notationExpiryHeader               = "io.cncf.notary.expiry"
+	notationX509Scheme                 = "notary.x509"
+	notationSigningAuthorityScheme     = "notary.x509.signingAuthority"
+	coseSign1Tag                       = 18
+	coseAlgorithmLabel                 = 1
+	coseX5ChainLabel                   = 33
+	maxEnvelopeSize                    = 4 << 20
+)
+
+// NotationVerifier verifies Notation (Notary v2) signatures against a local trust store and trust policy
+type NotationVerifier struct {
+	TrustStore  *TrustStore
+	TrustPolicy *TrustPolicyDocument
+	// Now returns the current time, used to enforce signature expiry (defaults to time.Now)
+	Now func() time.Time
+}
+
+func NewNotationVerifier(trustStore *TrustStore, trustPolicy *TrustPolicyDocument) *NotationVerifier {
+	return &NotationVerifier{TrustStore: trustStore, TrustPolicy: trustPolicy}
+}
+
+// notationEnvelope is the content of a JWS or COSE signature envelope
+type notationEnvelope struct {
+	payload       []byte
+	signingScheme string
+	signingTime   time.Time // asserted by the signer with the notary.x509 scheme
+	// authenticSigningTime is the signing time vouched by the signing authority (notary.x509.signingAuthority scheme)
+	authenticSigningTime time.Time
+	expiry               time.Time
+	certs                []*x509.Certificate
+	verify               func(key crypto.PublicKey) error
+}
+
+type notationPayload struct {
+	TargetArtifact v1.Descriptor `json:"targetArtifact"`
+}
+
+func (v *NotationVerifier) Verify(ctx context.Context, registry interfaces.IRegistry, repoName string, digest string, options ...remote.Option) (*Verdict, error) {
+	scope := registry.GetRegistry().Name() + "/" + repoName
+	policy := v.TrustPolicy.PolicyFor(scope)
+	if policy == nil {
+		return nil, fmt.Errorf("no trust policy applies to %s", scope)
+	}
+	if policy.SignatureVerification.Level == LevelSkip {
+		return &Verdict{Repository: repoName, Reference: digest, Digest: digest, Status: Skipped}, nil
+	}
+	referrers, err := registry.Referrers(ctx, repoName, digest, options...)
+	if err != nil {
+		return nil, err
+	}
+	var signatures []SignatureVerdict
+	for _, referrer := range referrers {
+		if referrer.ArtifactType != common.NotationSignatureArtifactType {
+			continue
+		}
+		artifact, err := fetchArtifact(ctx, registry, repoName, referrer.Digest, options...)
+		if err != nil {
+			return nil, err
+		}
+		manifest, err := artifact.Manifest()
+		if err != nil {
+			return nil, err
+		}
+		for _, descriptor := range manifest.Layers {
+			if descriptor.MediaType != NotationJWSMediaType && descriptor.MediaType != NotationCOSEMediaType {
+				continue
+			}
+			layer, err := artifact.LayerByDigest(descriptor.Digest)
+			if err != nil {
+				return nil, err
+			}
+			data, err := readEnvelope(layer)
+			if err != nil {
+				return nil, err
+			}
+			verdict := SignatureVerdict{Format: Notation, Digest: referrer.Digest}
+			verdict.Signer, verdict.Verified, verdict.Reason = v.verifyEnvelope(policy, descriptor.MediaType, data, digest)
+			signatures = append(signatures, verdict)
+		}
+	}
+	return newVerdict(repoName, digest, signatures), nil
+}
+
+// verifyEnvelope checks the integrity, the target and the authenticity of a signature envelope according to the policy level.
+// With the audit level authenticity and expiry failures are reported in reason without failing the signature
+func (v *NotationVerifier) verifyEnvelope(policy *TrustPolicy, mediaType types.MediaType, data []byte, digest string) (signer string, verified bool, reason string) {
+	var envelope *notationEnvelope
+	var err error
+	if mediaType == NotationJWSMediaType {
+		envelope, err = parseJWSEnvelope(data)
+	} else {
+		envelope, err = parseCOSEEnvelope(data)
+	}
+	if err != nil {
+		return "", false, fmt.Sprintf("malformed envelope: %v", err)
+	}
+	if envelope.signingScheme != notationX509Scheme && envelope.signingScheme != notationSigningAuthorityScheme {
+		return "", false, fmt.Sprintf("unsupported signing scheme %q", envelope.signingScheme)
+	}
+	if len(envelope.certs) == 0 {
+		return "", false, "envelope has no certificate chain"
+	}
+	leaf := envelope.certs[0]
+	signer = leaf.Subject.String()
+	if err := envelope.verify(leaf.PublicKey); err != nil {
+		return signer, false, fmt.Sprintf("integrity check failed: %v", err)
+	}
+	payload := notationPayload{}
+	if err := json.Unmarshal(envelope.payload, &payload); err != nil {
+		return signer, false, fmt.Sprintf("malformed payload: %v", err)
+	}
+	if payload.TargetArtifact.Digest.String() != digest {
+		return signer, false, fmt.Sprintf("payload is signed for %s", payload.TargetArtifact.Digest.String())
+	}
+	audit := policy.SignatureVerification.Level == LevelAudit
+	if reason = v.verifyAuthenticity(policy, envelope); reason != "" {
+		return signer, audit, reason
+	}
+	if policy.SignatureVerification.Level == LevelStrict && !envelope.expiry.IsZero() && v.now().After(envelope.expiry) {
+		return signer, false, fmt.Sprintf("signature expired at %s", envelope.expiry.Format(time.RFC3339))
+	}
+	return signer, true, ""
+}
+
+// verifyAuthenticity verifies the certificate chain against the policy trust stores of the signing scheme and the trusted identities.
+// The chain is verified at the authentic signing time of the signingAuthority scheme, the signing time of the notary.x509 scheme
+// is asserted by the signer (there is no timestamp countersignature) so the chain is verified at the current time
+func (v *NotationVerifier) verifyAuthenticity(policy *TrustPolicy, envelope *notationEnvelope) string {
+	leaf := envelope.certs[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range envelope.certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	storeType, verifyTime := TrustStoreCA, v.now()
+	if envelope.signingScheme == notationSigningAuthorityScheme {
+		if envelope.authenticSigningTime.IsZero() {
+			return "signing authority signature has no authentic signing time"
+		}
+		storeType, verifyTime = TrustStoreSigningAuthority, envelope.authenticSigningTime
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         policy.roots(v.TrustStore, storeType),
+		Intermediates: intermediates,
+		CurrentTime:   verifyTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return fmt.Sprintf("untrusted certificate: %v", err)
+	}
+	if !policy.trusts(leaf) {
+		return fmt.Sprintf("signer %s is not a trusted identity", leaf.Subject.String())
+	}
+	return ""
+}
+
+func (v *NotationVerifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func readEnvelope(layer v1.Layer) ([]byte, error) {
+	reader, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, maxEnvelopeSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxEnvelopeSize {
+		return nil, fmt.Errorf("signature envelope exceeds %d bytes", maxEnvelopeSize)
+	}
+	return data, nil
+}
+
+// jwsEnvelope is a flattened JWS JSON serialization
+type jwsEnvelope struct {
+	Payload   string `json:"payload"`
+	Protected string `json:"protected"`
+	Header    struct {
+		X5C [][]byte `json:"x5c"`
+	} `json:"header"`
+	Signature string `json:"signature"`
+}
+
+type jwsProtectedHeader struct {
+	Algorithm            string     `json:"alg"`
+	SigningScheme        string     `json:"io.cncf.notary.signingScheme"`
+	SigningTime          *time.Time `json:"io.cncf.notary.signingTime,omitempty"`
+	AuthenticSigningTime *time.Time `json:"io.cncf.notary.authenticSigningTime,omitempty"`
+	Expiry               *time.Time `json:"io.cncf.notary.expiry,omitempty"`
+}
+
+func parseJWSEnvelope(data []byte) (*notationEnvelope, error) {
+	jws := jwsEnvelope{}
+	if err := json.Unmarshal(data, &jws); err != nil {
+		return nil, err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if err != nil {
+		return nil, err
+	}
+	header := jwsProtectedHeader{}
+	if err := json.Unmarshal(protected, &header); err != nil {
+		return nil, err
+	}
+	envelope := &notationEnvelope{payload: payload, signingScheme: header.SigningScheme}
+	if header.SigningTime != nil {
+		envelope.signingTime = *header.SigningTime
+	}
+	if header.AuthenticSigningTime != nil {
+		envelope.authenticSigningTime = *header.AuthenticSigningTime
+	}
+	if header.Expiry != nil {
+		envelope.expiry = *header.Expiry
+	}
+	for _, der := range jws.Header.X5C {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		envelope.certs = append(envelope.certs, cert)
+	}
+	signingInput := []byte(jws.Protected + "." + jws.Payload)
+	envelope.verify = func(key crypto.PublicKey) error {
+		return verifyNotationSignature(header.Algorithm, key, signingInput, signature)
+	}
+	return envelope, nil
+}
+
+// coseSign1 is a COSE_Sign1 message (RFC 9052)
+type coseSign1 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected map[interface{}]interface{}
+	Payload     []byte
+	Signature   []byte
+}
+
+// COSE algorithm identifiers and their JWS names
+var coseAlgorithms = map[int64]string{
+	-37: "PS256",
+	-38: "PS384",
+	-39: "PS512",
+	-7:  "ES256",
+	-35: "ES384",
+	-36: "ES512",
+}
+
+func parseCOSEEnvelope(data []byte) (*notationEnvelope, error) {
+	tag := cbor.RawTag{}
+	if err := cbor.Unmarshal(data, &tag); err != nil {
+		return nil, err
+	}
+	if tag.Number != coseSign1Tag {
+		return nil, fmt.Errorf("unexpected CBOR tag %d", tag.Number)
+	}
+	message := coseSign1{}
+	if err := cbor.Unmarshal(tag.Content, &message); err != nil {
+		return nil, err
+	}
+	protected := map[interface{}]interface{}{}
+	if err := cbor.Unmarshal(message.Protected, &protected); err != nil {
+		return nil, err
+	}
+	algorithmID, ok := coseInt(protected[uint64(coseAlgorithmLabel)])
+	algorithm, known := coseAlgorithms[algorithmID]
+	if !ok || !known {
+		return nil, fmt.Errorf("unsupported COSE algorithm %v", protected[uint64(coseAlgorithmLabel)])
+	}
+	envelope := &notationEnvelope{payload: message.Payload}
+	envelope.signingScheme, _ = protected[notationSigningSchemeHeader].(string)
+	envelope.signingTime, _ = coseTime(protected[notationSigningTimeHeader])
+	envelope.authenticSigningTime, _ = coseTime(protected[notationAuthenticSigningTimeHeader])
+	envelope.expiry, _ = coseTime(protected[notationExpiryHeader])
+	var chain [][]byte
+	switch x5chain := message.Unprotected[uint64(coseX5ChainLabel)].(type) {
+	case []byte:
+		chain = [][]byte{x5chain}
+	case []interface{}:
+		for _, item := range x5chain {
+			der, ok := item.([]byte)
+			if !ok {
+				return nil, errors.New("malformed x5chain")
+			}
+			chain = append(chain, der)
+		}
+	}
+	for _, der := range chain {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		envelope.certs = append(envelope.certs, cert)
+	}
+	signingInput, err := cbor.Marshal([]interface{}{"Signature1", message.Protected, []byte{}, message.Payload})
+	if err != nil {
+		return nil, err
+	}
+	envelope.verify = func(key crypto.PublicKey) error {
+		return verifyNotationSignature(algorithm, key, signingInput, message.Signature)
+	}
+	return envelope, nil
+}
+
+func coseInt(value interface{}) (int64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return value, true
+	case uint64:
+		return int64(value), true
+	}
+	return 0, false
+}
+
+// coseTime decodes a CBOR date (tag 1 epoch time, tag 0 RFC 3339 string or a plain epoch)
+func coseTime(value interface{}) (time.Time, bool) {
+	switch value := value.(type) {
+	case time.Time:
+		return value, true
+	case cbor.Tag:
+		return coseTime(value.Content)
+	case string:
+		t, err := time.Parse(time.RFC3339, value)
+		return t, err == nil
+	case float64:
+		return time.Unix(0, int64(value*float64(time.Second))), true
+	}
+	if seconds, ok := coseInt(value); ok {
+		return time.Unix(seconds, 0), true
+	}
+	return time.Time{}, false
+}
+
+// verifyNotationSignature verifies a JWS/COSE signature, ECDSA signatures are the raw r||s concatenation
+func verifyNotationSignature(algorithm string, key crypto.PublicKey, signingInput, signature []byte) error {
+	if len(algorithm) != len("PS256") {
+		return fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+	var hash crypto.Hash
+	switch algorithm[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+	hasher := hash.New()
+	hasher.Write(signingInput)
+	hashed := hasher.Sum(nil)
+	switch algorithm[:2] {
+	case "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires an RSA key", algorithm)
+		}
+		return rsa.VerifyPSS(rsaKey, hash, hashed, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires an ECDSA key", algorithm)
+		}
+		size := len(signature) / 2
+		if size == 0 || len(signature)%2 != 0 {
+			return errors.New("malformed ECDSA signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, hashed, r, s) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm %s", algorithm)
+}
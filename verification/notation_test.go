@@ -0,0 +1,311 @@
+package verification
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armosec/registryx/common"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCertificate(t *testing.T, subject pkix.Name, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	return newCertificateValidity(t, subject, key, parent, parentKey, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+}
+
+func newCertificateValidity(t *testing.T, subject pkix.Name, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer, notBefore, notAfter time.Time) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	} else {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return cert
+}
+
+func notationPayloadFor(t *testing.T, digest string) []byte {
+	payload, err := json.Marshal(map[string]interface{}{"targetArtifact": map[string]interface{}{"mediaType": string(types.OCIManifestSchema1), "digest": digest, "size": 1}})
+	assert.Nil(t, err)
+	return payload
+}
+
+// signES256 returns the raw r||s ES256 signature of data
+func signES256(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	hash := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	assert.Nil(t, err)
+	return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+}
+
+func jwsSignature(t *testing.T, key *ecdsa.PrivateKey, chain []*x509.Certificate, digest string, expiry time.Time) []byte {
+	return jwsSignatureWithHeader(t, key, chain, digest, map[string]interface{}{
+		notationSigningSchemeHeader: notationX509Scheme,
+		notationSigningTimeHeader:   time.Now().Format(time.RFC3339),
+		notationExpiryHeader:        expiry.Format(time.RFC3339),
+	})
+}
+
+// jwsSignatureWithHeader signs digest with the notary headers of header
+func jwsSignatureWithHeader(t *testing.T, key *ecdsa.PrivateKey, chain []*x509.Certificate, digest string, header map[string]interface{}) []byte {
+	header["alg"] = "ES256"
+	header["cty"] = "application/vnd.cncf.notary.payload.v1+json"
+	header["crit"] = []string{notationSigningSchemeHeader}
+	protected, err := json.Marshal(header)
+	assert.Nil(t, err)
+	encodedProtected := base64.RawURLEncoding.EncodeToString(protected)
+	encodedPayload := base64.RawURLEncoding.EncodeToString(notationPayloadFor(t, digest))
+	x5c := [][]byte{}
+	for _, cert := range chain {
+		x5c = append(x5c, cert.Raw)
+	}
+	envelope, err := json.Marshal(map[string]interface{}{
+		"payload":   encodedPayload,
+		"protected": encodedProtected,
+		"header":    map[string]interface{}{"x5c": x5c},
+		"signature": base64.RawURLEncoding.EncodeToString(signES256(t, key, []byte(encodedProtected+"."+encodedPayload))),
+	})
+	assert.Nil(t, err)
+	return envelope
+}
+
+func coseSignature(t *testing.T, key *rsa.PrivateKey, chain []*x509.Certificate, digest string) []byte {
+	protected, err := cbor.Marshal(map[interface{}]interface{}{
+		coseAlgorithmLabel:          -37, // PS256
+		notationSigningSchemeHeader: notationX509Scheme,
+		notationSigningTimeHeader:   cbor.Tag{Number: 1, Content: time.Now().Unix()},
+	})
+	assert.Nil(t, err)
+	payload := notationPayloadFor(t, digest)
+	toBeSigned, err := cbor.Marshal([]interface{}{"Signature1", protected, []byte{}, payload})
+	assert.Nil(t, err)
+	hash := sha256.Sum256(toBeSigned)
+	signature, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, hash[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	assert.Nil(t, err)
+	x5chain := []interface{}{}
+	for _, cert := range chain {
+		x5chain = append(x5chain, cert.Raw)
+	}
+	envelope, err := cbor.Marshal(cbor.Tag{Number: coseSign1Tag, Content: []interface{}{
+		protected,
+		map[interface{}]interface{}{coseX5ChainLabel: x5chain},
+		payload,
+		signature,
+	}})
+	assert.Nil(t, err)
+	return envelope
+}
+
+// pushNotationSignature pushes a notation signature artifact referring to digest
+func pushNotationSignature(t *testing.T, repo *name.Repository, digest string, envelope []byte, mediaType types.MediaType) {
+	descriptor, err := remote.Head(repo.Digest(digest))
+	assert.Nil(t, err)
+	image, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer(envelope, mediaType)})
+	assert.Nil(t, err)
+	image = mutate.MediaType(image, types.OCIManifestSchema1)
+	image = mutate.ConfigMediaType(image, common.NotationSignatureArtifactType)
+	image = mutate.Subject(image, *descriptor).(v1.Image)
+	signatureDigest, err := image.Digest()
+	assert.Nil(t, err)
+	assert.Nil(t, remote.Write(repo.Digest(signatureDigest.String()), image))
+}
+
+func TestNotationVerifier(t *testing.T) {
+	iRegistry, server := newInMemoryRegistry(t)
+	defer server.Close()
+	registryName := iRegistry.GetRegistry().Name()
+	ctx := context.Background()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ca := newCertificate(t, pkix.Name{CommonName: "armo ca"}, caKey, nil, nil)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ecLeaf := newCertificate(t, pkix.Name{Organization: []string{"armo"}, CommonName: "release"}, ecKey, ca, caKey)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	rsaLeaf := newCertificate(t, pkix.Name{Organization: []string{"armo"}, CommonName: "release"}, rsaKey, ca, caKey)
+	otherLeaf := newCertificate(t, pkix.Name{Organization: []string{"other"}, CommonName: "dev"}, ecKey, ca, caKey)
+
+	//trust store directory in the notation layout
+	dir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "x509", "ca", "armo"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "x509", "ca", "armo", "root.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0644))
+	trustStore, err := LoadTrustStore(dir)
+	assert.Nil(t, err)
+	assert.Len(t, trustStore.Certificates("ca:armo"), 1)
+
+	trustPolicy, err := ParseTrustPolicy([]byte(`{
+		"version": "1.0",
+		"trustPolicies": [
+			{"name": "audit", "registryScopes": ["` + registryName + `/audited"], "signatureVerification": {"level": "audit"}, "trustStores": ["ca:armo"], "trustedIdentities": ["x509.subject: O=armo, CN=release"]},
+			{"name": "skip", "registryScopes": ["` + registryName + `/skipped"], "signatureVerification": {"level": "skip"}},
+			{"name": "default", "registryScopes": ["*"], "signatureVerification": {"level": "strict"}, "trustStores": ["ca:armo"], "trustedIdentities": ["x509.subject: O=armo, CN=release"]}
+		]
+	}`))
+	assert.Nil(t, err)
+	verifier := NewNotationVerifier(trustStore, trustPolicy)
+
+	repo, err := common.MakeRepoWithRegistry("my-repo", iRegistry.GetRegistry())
+	assert.Nil(t, err)
+	jwsSigned := pushImage(t, repo, "jws")
+	pushNotationSignature(t, repo, jwsSigned, jwsSignature(t, ecKey, []*x509.Certificate{ecLeaf, ca}, jwsSigned, time.Now().Add(time.Hour)), NotationJWSMediaType)
+	coseSigned := pushImage(t, repo, "cose")
+	pushNotationSignature(t, repo, coseSigned, coseSignature(t, rsaKey, []*x509.Certificate{rsaLeaf}, coseSigned), NotationCOSEMediaType)
+	untrusted := pushImage(t, repo, "untrusted")
+	pushNotationSignature(t, repo, untrusted, jwsSignature(t, ecKey, []*x509.Certificate{otherLeaf}, untrusted, time.Now().Add(time.Hour)), NotationJWSMediaType)
+	expired := pushImage(t, repo, "expired")
+	pushNotationSignature(t, repo, expired, jwsSignature(t, ecKey, []*x509.Certificate{ecLeaf}, expired, time.Now().Add(-time.Minute)), NotationJWSMediaType)
+	wrongDigest := pushImage(t, repo, "wrong-digest")
+	pushNotationSignature(t, repo, wrongDigest, jwsSignature(t, ecKey, []*x509.Certificate{ecLeaf}, jwsSigned, time.Now().Add(time.Hour)), NotationJWSMediaType)
+
+	verdict, err := verifier.Verify(ctx, iRegistry, "my-repo", jwsSigned)
+	assert.Nil(t, err)
+	assert.Equal(t, Verified, verdict.Status)
+	assert.Equal(t, Notation, verdict.Signatures[0].Format)
+	assert.Equal(t, "CN=release,O=armo", verdict.Signatures[0].Signer)
+
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", coseSigned)
+	assert.Nil(t, err)
+	assert.Equal(t, Verified, verdict.Status)
+
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", untrusted)
+	assert.Nil(t, err)
+	assert.Equal(t, Invalid, verdict.Status)
+	assert.Contains(t, verdict.Signatures[0].Reason, "is not a trusted identity")
+
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", expired)
+	assert.Nil(t, err)
+	assert.Equal(t, Invalid, verdict.Status)
+	assert.Contains(t, verdict.Signatures[0].Reason, "signature expired")
+
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", wrongDigest)
+	assert.Nil(t, err)
+	assert.Equal(t, Invalid, verdict.Status)
+	assert.Contains(t, verdict.Signatures[0].Reason, "payload is signed for")
+
+	//cosign signatures are ignored by the notation verifier
+	cosignKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	cosignSigned := pushImage(t, repo, "cosign")
+	pushCosignSignature(t, repo, cosignKey, cosignSigned, cosignSigned)
+	verdict, err = verifier.Verify(ctx, iRegistry, "my-repo", cosignSigned)
+	assert.Nil(t, err)
+	assert.Equal(t, Unsigned, verdict.Status)
+
+	//audit level logs authenticity failures without failing the signature
+	auditedRepo, err := common.MakeRepoWithRegistry("audited", iRegistry.GetRegistry())
+	assert.Nil(t, err)
+	audited := pushImage(t, auditedRepo, "v1")
+	pushNotationSignature(t, auditedRepo, audited, jwsSignature(t, ecKey, []*x509.Certificate{otherLeaf}, audited, time.Now().Add(time.Hour)), NotationJWSMediaType)
+	verdict, err = verifier.Verify(ctx, iRegistry, "audited", audited)
+	assert.Nil(t, err)
+	assert.Equal(t, Verified, verdict.Status)
+	assert.Contains(t, verdict.Signatures[0].Reason, "is not a trusted identity")
+
+	verdict, err = verifier.Verify(ctx, iRegistry, "skipped", jwsSigned)
+	assert.Nil(t, err)
+	assert.Equal(t, Skipped, verdict.Status)
+
+	_, err = ParseTrustPolicy([]byte(`{"version": "1.0", "trustPolicies": [{"name": "x", "registryScopes": ["*"], "signatureVerification": {"level": "lax"}}]}`))
+	assert.Error(t, err)
+}
+
+func TestNotationVerificationTime(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ca := newCertificateValidity(t, pkix.Name{CommonName: "armo ca"}, caKey, nil, nil, time.Now().Add(-72*time.Hour), time.Now().Add(time.Hour))
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	//the leaf expired yesterday
+	expiredLeaf := newCertificateValidity(t, pkix.Name{CommonName: "release"}, leafKey, ca, caKey, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour))
+	validLeaf := newCertificate(t, pkix.Name{CommonName: "release"}, leafKey, ca, caKey)
+	withinValidity := time.Now().Add(-36 * time.Hour).Format(time.RFC3339)
+
+	trustStore := NewTrustStore()
+	trustStore.Add(TrustStoreCA, "armo", ca)
+	trustStore.Add(TrustStoreSigningAuthority, "tsa", ca)
+	policy := &TrustPolicy{
+		SignatureVerification: SignatureVerification{Level: LevelStrict},
+		TrustStores:           []string{"ca:armo"},
+		TrustedIdentities:     []string{wildcard},
+	}
+	verifier := NewNotationVerifier(trustStore, &TrustPolicyDocument{TrustPolicies: []TrustPolicy{*policy}})
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	//the signer cannot backdate a notary.x509 signature into the validity of an expired certificate
+	backdated := jwsSignatureWithHeader(t, leafKey, []*x509.Certificate{expiredLeaf}, digest, map[string]interface{}{
+		notationSigningSchemeHeader: notationX509Scheme,
+		notationSigningTimeHeader:   withinValidity,
+	})
+	_, verified, reason := verifier.verifyEnvelope(policy, NotationJWSMediaType, backdated, digest)
+	assert.False(t, verified)
+	assert.Contains(t, reason, "untrusted certificate")
+
+	//the authentic signing time of a signing authority is trusted
+	signingAuthority := map[string]interface{}{
+		notationSigningSchemeHeader:        notationSigningAuthorityScheme,
+		notationAuthenticSigningTimeHeader: withinValidity,
+	}
+	authentic := jwsSignatureWithHeader(t, leafKey, []*x509.Certificate{expiredLeaf}, digest, signingAuthority)
+	_, verified, reason = verifier.verifyEnvelope(policy, NotationJWSMediaType, authentic, digest)
+	assert.False(t, verified, "signing authority signatures are verified only against signingAuthority trust stores")
+	assert.Contains(t, reason, "untrusted certificate")
+	policy.TrustStores = []string{"signingAuthority:tsa"}
+	_, verified, reason = verifier.verifyEnvelope(policy, NotationJWSMediaType, authentic, digest)
+	assert.True(t, verified, reason)
+
+	//and notary.x509 signatures are verified only against ca trust stores
+	current := jwsSignatureWithHeader(t, leafKey, []*x509.Certificate{validLeaf}, digest, map[string]interface{}{
+		notationSigningSchemeHeader: notationX509Scheme,
+		notationSigningTimeHeader:   time.Now().Format(time.RFC3339),
+	})
+	_, verified, _ = verifier.verifyEnvelope(policy, NotationJWSMediaType, current, digest)
+	assert.False(t, verified)
+	policy.TrustStores = []string{"ca:armo"}
+	_, verified, reason = verifier.verifyEnvelope(policy, NotationJWSMediaType, current, digest)
+	assert.True(t, verified, reason)
+
+	unauthenticated := jwsSignatureWithHeader(t, leafKey, []*x509.Certificate{validLeaf}, digest, map[string]interface{}{
+		notationSigningSchemeHeader: notationSigningAuthorityScheme,
+		notationSigningTimeHeader:   time.Now().Format(time.RFC3339),
+	})
+	_, verified, reason = verifier.verifyEnvelope(policy, NotationJWSMediaType, unauthenticated, digest)
+	assert.False(t, verified)
+	assert.Contains(t, reason, "no authentic signing time")
+}
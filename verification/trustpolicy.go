@@ -0,0 +1,201 @@
+package verification
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// notation trust store types
+const (
+	TrustStoreCA               = "ca"
+	TrustStoreSigningAuthority = "signingAuthority"
+)
+
+// notation signature verification levels
+const (
+	LevelStrict     = "strict"
+	LevelPermissive = "permissive"
+	LevelAudit      = "audit"
+	LevelSkip       = "skip"
+)
+
+const wildcard = "*"
+
+// TrustStore holds the named notation trust stores ("<type>:<name>") and their certificates
+type TrustStore struct {
+	stores map[string][]*x509.Certificate
+}
+
+func NewTrustStore() *TrustStore {
+	return &TrustStore{stores: map[string][]*x509.Certificate{}}
+}
+
+// LoadTrustStore loads a notation trust store directory laid out as x509/<type>/<name>/<certificate files>
+func LoadTrustStore(dir string) (*TrustStore, error) {
+	trustStore := NewTrustStore()
+	for _, storeType := range []string{TrustStoreCA, TrustStoreSigningAuthority} {
+		typeDir := filepath.Join(dir, "x509", storeType)
+		stores, err := os.ReadDir(typeDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, store := range stores {
+			if !store.IsDir() {
+				continue
+			}
+			files, err := os.ReadDir(filepath.Join(typeDir, store.Name()))
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				if file.IsDir() {
+					continue
+				}
+				data, err := os.ReadFile(filepath.Join(typeDir, store.Name(), file.Name()))
+				if err != nil {
+					return nil, err
+				}
+				certs, err := parseCertificateFile(data)
+				if err != nil {
+					return nil, fmt.Errorf("trust store %s:%s: %s: %w", storeType, store.Name(), file.Name(), err)
+				}
+				trustStore.Add(storeType, store.Name(), certs...)
+			}
+		}
+	}
+	return trustStore, nil
+}
+
+func (s *TrustStore) Add(storeType, name string, certs ...*x509.Certificate) {
+	key := storeType + ":" + name
+	s.stores[key] = append(s.stores[key], certs...)
+}
+
+// Certificates returns the certificates of a trust store referenced as "<type>:<name>"
+func (s *TrustStore) Certificates(ref string) []*x509.Certificate {
+	return s.stores[ref]
+}
+
+// parseCertificateFile parses PEM or DER encoded certificates
+func parseCertificateFile(data []byte) ([]*x509.Certificate, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		return parseCertificates(data)
+	}
+	return x509.ParseCertificates(data)
+}
+
+// TrustPolicyDocument is a notation trust policy (trustpolicy.json)
+type TrustPolicyDocument struct {
+	Version       string        `json:"version"`
+	TrustPolicies []TrustPolicy `json:"trustPolicies"`
+}
+
+type TrustPolicy struct {
+	Name                  string                `json:"name"`
+	RegistryScopes        []string              `json:"registryScopes"`
+	SignatureVerification SignatureVerification `json:"signatureVerification"`
+	TrustStores           []string              `json:"trustStores,omitempty"`
+	TrustedIdentities     []string              `json:"trustedIdentities,omitempty"`
+}
+
+type SignatureVerification struct {
+	Level string `json:"level"`
+}
+
+func ParseTrustPolicy(data []byte) (*TrustPolicyDocument, error) {
+	document := &TrustPolicyDocument{}
+	if err := json.Unmarshal(data, document); err != nil {
+		return nil, err
+	}
+	for _, policy := range document.TrustPolicies {
+		switch policy.SignatureVerification.Level {
+		case LevelStrict, LevelPermissive, LevelAudit, LevelSkip:
+		default:
+			return nil, fmt.Errorf("trust policy %s: unknown verification level %q", policy.Name, policy.SignatureVerification.Level)
+		}
+		for _, identity := range policy.TrustedIdentities {
+			if identity != wildcard && !strings.HasPrefix(identity, x509SubjectPrefix) {
+				return nil, fmt.Errorf("trust policy %s: unsupported trusted identity %q", policy.Name, identity)
+			}
+		}
+	}
+	return document, nil
+}
+
+// PolicyFor returns the trust policy of an artifact scope (<registry>/<repository>),
+// a policy listing the scope explicitly takes precedence over the "*" policy
+func (d *TrustPolicyDocument) PolicyFor(scope string) *TrustPolicy {
+	var wildcardPolicy *TrustPolicy
+	for i := range d.TrustPolicies {
+		for _, registryScope := range d.TrustPolicies[i].RegistryScopes {
+			if registryScope == scope {
+				return &d.TrustPolicies[i]
+			}
+			if registryScope == wildcard {
+				wildcardPolicy = &d.TrustPolicies[i]
+			}
+		}
+	}
+	return wildcardPolicy
+}
+
+// roots returns the certificates of the trust stores of storeType the policy trusts
+func (p *TrustPolicy) roots(trustStore *TrustStore, storeType string) *x509.CertPool {
+	roots := x509.NewCertPool()
+	for _, ref := range p.TrustStores {
+		if !strings.HasPrefix(ref, storeType+":") {
+			continue
+		}
+		for _, cert := range trustStore.Certificates(ref) {
+			roots.AddCert(cert)
+		}
+	}
+	return roots
+}
+
+const x509SubjectPrefix = "x509.subject:"
+
+// trusts reports whether the signing certificate matches one of the trusted identities,
+// an identity matches when every attribute of its distinguished name is equal in the certificate subject
+func (p *TrustPolicy) trusts(cert *x509.Certificate) bool {
+	for _, identity := range p.TrustedIdentities {
+		if identity == wildcard {
+			return true
+		}
+		if subjectMatches(strings.TrimSpace(strings.TrimPrefix(identity, x509SubjectPrefix)), cert) {
+			return true
+		}
+	}
+	return false
+}
+
+func subjectMatches(dn string, cert *x509.Certificate) bool {
+	subject := map[string][]string{
+		"C":  cert.Subject.Country,
+		"ST": cert.Subject.Province,
+		"L":  cert.Subject.Locality,
+		"O":  cert.Subject.Organization,
+		"OU": cert.Subject.OrganizationalUnit,
+		"CN": {cert.Subject.CommonName},
+	}
+	for _, attribute := range strings.Split(dn, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(attribute), "=")
+		if !ok {
+			return false
+		}
+		values, ok := subject[strings.ToUpper(strings.TrimSpace(key))]
+		if !ok || !slices.Contains(values, strings.TrimSpace(value)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package verification
+
+import (
+	"context"
+	"sync"
+
+	"github.com/armosec/registryx/common"
+	"github.com/armosec/registryx/interfaces"
+	"github.com/armosec/registryx/registries"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const defaultVerifyWorkers = 10
+
+type SignatureFormat string
+
+const (
+	Cosign   SignatureFormat = "cosign"
+	Notation SignatureFormat = "notation"
+)
+
+type VerdictStatus string
+
+const (
+	// Verified - at least one signature verified against the trusted keys
+	Verified VerdictStatus = "verified"
+	// Unsigned - no signature of the verifier format is attached to the image
+	Unsigned VerdictStatus = "unsigned"
+	// Invalid - signatures are attached but none of them verified
+	Invalid VerdictStatus = "invalid"
+	// Skipped - the trust policy of the image skips verification
+	Skipped VerdictStatus = "skipped"
+	// Failed - the verification could not be completed (see Verdict.Err)
+	Failed VerdictStatus = "failed"
+)
+
+// SignatureVerdict is the verification result of a single signature artifact
+type SignatureVerdict struct {
+	Format   SignatureFormat
+	Digest   string // digest of the signature manifest
+	Signer   string // key fingerprint or certificate subject that verified the signature
+	Verified bool
+	Reason   string // why the signature did not verify
+}
+
+// Verdict is the verification result of an image
+type Verdict struct {
+	Repository string
+	Reference  string
+	Digest     string
+	Status     VerdictStatus
+	Signatures []SignatureVerdict
+	Err        error
+}
+
+func (v *Verdict) Verified() bool {
+	return v.Status == Verified
+}
+
+// Verifier verifies the signatures attached to an image digest
+type Verifier interface {
+	Verify(ctx context.Context, registry interfaces.IRegistry, repoName string, digest string, options ...remote.Option) (*Verdict, error)
+}
+
+// VerifyAll resolves the references (e.g. the latest tags returned by GetImagesToScan) to digests and verifies them concurrently
+// using at most workers parallel verifications, verdicts are returned in the order of the requests
+func VerifyAll(ctx context.Context, registry interfaces.IRegistry, verifier Verifier, requests []registries.ResolveRequest, workers int, options ...remote.Option) []Verdict {
+	if workers <= 0 {
+		workers = defaultVerifyWorkers
+	}
+	verdicts := make([]Verdict, len(requests))
+	resolved := registries.ResolveAll(ctx, registry, requests, workers, options...)
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for range min(workers, len(requests)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				verdicts[i] = verify(ctx, registry, verifier, resolved[i], options...)
+			}
+		}()
+	}
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return verdicts
+}
+
+func verify(ctx context.Context, registry interfaces.IRegistry, verifier Verifier, resolved registries.ResolveResult, options ...remote.Option) Verdict {
+	failed := Verdict{Repository: resolved.Repository, Reference: resolved.Reference, Digest: resolved.Digest, Status: Failed, Err: resolved.Err}
+	if resolved.Err != nil {
+		return failed
+	}
+	if failed.Err = ctx.Err(); failed.Err != nil {
+		return failed
+	}
+	verdict, err := verifier.Verify(ctx, registry, resolved.Repository, resolved.Digest, options...)
+	if err != nil {
+		failed.Err = err
+		return failed
+	}
+	verdict.Reference = resolved.Reference
+	return *verdict
+}
+
+// newVerdict summarizes the signature verdicts of an image
+func newVerdict(repoName, digest string, signatures []SignatureVerdict) *Verdict {
+	verdict := &Verdict{Repository: repoName, Reference: digest, Digest: digest, Signatures: signatures, Status: Unsigned}
+	if len(signatures) > 0 {
+		verdict.Status = Invalid
+	}
+	for _, signature := range signatures {
+		if signature.Verified {
+			verdict.Status = Verified
+			break
+		}
+	}
+	return verdict
+}
+
+// fetchArtifact returns the manifest and blobs of a signature artifact stored in repoName
+func fetchArtifact(ctx context.Context, registry interfaces.IRegistry, repoName, digest string, options ...remote.Option) (v1.Image, error) {
+	ref, err := common.MakeReference(repoName, digest, registry.GetRegistry())
+	if err != nil {
+		return nil, err
+	}
+	//the options are shared by the VerifyAll workers, they are copied before appending
+	return remote.Image(ref, append(options[:len(options):len(options)], remote.WithContext(ctx))...)
+}